@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a whole run: every provider is vacuumed in turn, each one
+// to its own output.
+type Config struct {
+	Concurrency  int        `yaml:"concurrency"`
+	ReportFile   string     `yaml:"report_file"`
+	ReportFormat string     `yaml:"report_format"`
+	StateFile    string     `yaml:"state_file"`
+	Providers    []Provider `yaml:"providers"`
+}
+
+type Provider struct {
+	// Name identifies the provider in logs, it defaults to its type.
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Endpoint string `yaml:"endpoint"`
+	Username string `yaml:"username"`
+	// TokenEnv is the environment variable holding the access token, so that
+	// secrets stay out of the configuration file.
+	TokenEnv    string   `yaml:"token_env"`
+	AccessToken string   `yaml:"-"`
+	Orgs        []string `yaml:"orgs"`
+	Users       []string `yaml:"users"`
+	Filters     Filters  `yaml:"filters"`
+	// FetchLanguages costs a request per repository on GitLab.
+	FetchLanguages bool `yaml:"fetch_languages"`
+	// Output is a shorthand for a single item of Outputs. Every repository
+	// is handed over to each output.
+	Output  Output   `yaml:"output"`
+	Outputs []Output `yaml:"outputs"`
+}
+
+// Filters select the repositories of the provider, glob patterns and regular
+// expressions being matched against their path.
+type Filters struct {
+	Include      []string `yaml:"include"`
+	Exclude      []string `yaml:"exclude"`
+	IncludeRegex []string `yaml:"include_regex"`
+	ExcludeRegex []string `yaml:"exclude_regex"`
+	SkipForks    bool     `yaml:"skip_forks"`
+	SkipArchived bool     `yaml:"skip_archived"`
+	Visibility   string   `yaml:"visibility"`
+}
+
+type Output struct {
+	Format               string   `yaml:"format"`
+	File                 string   `yaml:"file"`
+	Columns              []string `yaml:"columns"`
+	Folder               string   `yaml:"folder"`
+	SSHKeys              []string `yaml:"ssh_keys"`
+	SSHKeyPassphraseFile string   `yaml:"ssh_key_passphrase_file"`
+	SSHKeyPassphraseEnv  string   `yaml:"ssh_key_passphrase_env"`
+	KnownHosts           string   `yaml:"known_hosts"`
+	HostKeyPolicy        string   `yaml:"ssh_host_key_policy"`
+	Sync                 bool     `yaml:"sync"`
+	Mirror               bool     `yaml:"mirror"`
+	Depth                int      `yaml:"depth"`
+	SingleBranch         bool     `yaml:"single_branch"`
+	SkipUnchanged        bool     `yaml:"skip_unchanged"`
+	Prune                bool     `yaml:"prune"`
+	PrunePolicy          string   `yaml:"prune_policy"`
+}
+
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c Config
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	if len(c.Providers) == 0 {
+		return nil, fmt.Errorf("config %s: no provider defined", path)
+	}
+
+	// Labels tell providers apart in the summary of a run, and in the rows of
+	// SQLite databases.
+	labels := map[string]bool{}
+	for i := range c.Providers {
+		p := &c.Providers[i]
+
+		if labels[p.Label()] {
+			return nil, fmt.Errorf("config %s: several providers are labelled %s, set the name of each one", path, p.Label())
+		}
+		labels[p.Label()] = true
+
+		if len(p.Outputs) == 0 {
+			p.Outputs = []Output{p.Output}
+		} else if !reflect.DeepEqual(p.Output, Output{}) {
+			return nil, fmt.Errorf("config %s: provider %s should define either output or outputs", path, p.Label())
+		}
+		p.Output = Output{}
+
+		if strings.TrimSpace(p.TokenEnv) != "" {
+			p.AccessToken = os.Getenv(p.TokenEnv)
+			if p.AccessToken == "" {
+				return nil, fmt.Errorf("config %s: environment variable %s of provider %s is empty", path, p.TokenEnv, p.Label())
+			}
+		}
+	}
+
+	return &c, nil
+}
+
+// Label returns the name of the provider, or its type and endpoint.
+func (p Provider) Label() string {
+	if strings.TrimSpace(p.Name) != "" {
+		return p.Name
+	}
+
+	if strings.TrimSpace(p.Endpoint) != "" {
+		return p.Type + " (" + p.Endpoint + ")"
+	}
+
+	return p.Type
+}
@@ -0,0 +1,141 @@
+package vacuum
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+type FilterOptions struct {
+	// Include and Exclude are glob patterns matched against the path of the
+	// repositories, e.g. "my-org/*". IncludeRegex and ExcludeRegex are regular
+	// expressions matched against the same path.
+	Include      []string
+	Exclude      []string
+	IncludeRegex []string
+	ExcludeRegex []string
+	SkipForks    bool
+	SkipArchived bool
+	// Visibility keeps only the repositories of the given visibility, one of
+	// the provider.VISIBILITY_* constants.
+	Visibility string
+}
+
+// Filter selects the repositories handed over to the output. When include
+// patterns are given, a repository must match at least one of them; it must
+// not match any exclude pattern.
+type Filter struct {
+	opts         FilterOptions
+	includeRegex []*regexp.Regexp
+	excludeRegex []*regexp.Regexp
+}
+
+func NewFilter(opts FilterOptions) (*Filter, error) {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+
+	includeRegex, err := compileRegexps(opts.IncludeRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeRegex, err := compileRegexps(opts.ExcludeRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Visibility {
+	case "", provider.VISIBILITY_PUBLIC, provider.VISIBILITY_PRIVATE, provider.VISIBILITY_INTERNAL:
+	default:
+		return nil, errors.New("Unknown visibility.")
+	}
+
+	return &Filter{opts, includeRegex, excludeRegex}, nil
+}
+
+func compileRegexps(patterns []string) ([]*regexp.Regexp, error) {
+	var r []*regexp.Regexp
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+
+		r = append(r, re)
+	}
+
+	return r, nil
+}
+
+// apply returns the repositories kept by the filter, in order.
+func (f *Filter) apply(repos []provider.Repository) []provider.Repository {
+	if f == nil {
+		return repos
+	}
+
+	var r []provider.Repository
+	for _, repo := range repos {
+		if reason := f.reject(repo); reason != "" {
+			log.Debugf("Skipping repository %s: %s", repo.Fullname(), reason)
+			continue
+		}
+
+		r = append(r, repo)
+	}
+
+	return r
+}
+
+// reject returns why the repository is filtered out, or an empty string when
+// it is kept.
+func (f *Filter) reject(r provider.Repository) string {
+	if f.opts.SkipForks && r.Fork {
+		return "fork"
+	}
+
+	if f.opts.SkipArchived && r.Archived {
+		return "archived"
+	}
+
+	if f.opts.Visibility != "" && r.Visibility != f.opts.Visibility {
+		if r.Visibility == "" {
+			return "unknown visibility"
+		}
+
+		return r.Visibility + " visibility"
+	}
+
+	if (len(f.opts.Include) > 0 || len(f.includeRegex) > 0) && !matchAny(r.Path, f.opts.Include, f.includeRegex) {
+		return "not included"
+	}
+
+	if matchAny(r.Path, f.opts.Exclude, f.excludeRegex) {
+		return "excluded"
+	}
+
+	return ""
+}
+
+func matchAny(name string, globs []string, regexps []*regexp.Regexp) bool {
+	for _, pattern := range globs {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	for _, re := range regexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
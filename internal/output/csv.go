@@ -0,0 +1,124 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+)
+
+// csvDefaultColumns are the columns written when none are configured, they
+// can be read back by the file provider.
+var csvDefaultColumns = []string{"provider", "owner", "path", "name", "clone_url", "ssh_url", "default_branch", "visibility", "fork", "archived"}
+
+var csvColumns = map[string]func(r inventoryRecord) string{
+	"provider":       func(r inventoryRecord) string { return r.Provider },
+	"owner":          func(r inventoryRecord) string { return r.Owner },
+	"path":           func(r inventoryRecord) string { return r.Path },
+	"name":           func(r inventoryRecord) string { return r.Name },
+	"clone_url":      func(r inventoryRecord) string { return r.CloneURL },
+	"ssh_url":        func(r inventoryRecord) string { return r.SSHUrl },
+	"default_branch": func(r inventoryRecord) string { return r.DefaultBranch },
+	"description":    func(r inventoryRecord) string { return r.Description },
+	"topics":         func(r inventoryRecord) string { return strings.Join(r.Topics, ",") },
+	"language":       func(r inventoryRecord) string { return r.Language },
+	"size":           func(r inventoryRecord) string { return strconv.FormatInt(r.Size, 10) },
+	"stars":          func(r inventoryRecord) string { return strconv.Itoa(r.Stars) },
+	"fork":           func(r inventoryRecord) string { return strconv.FormatBool(r.Fork) },
+	"archived":       func(r inventoryRecord) string { return strconv.FormatBool(r.Archived) },
+	"template":       func(r inventoryRecord) string { return strconv.FormatBool(r.Template) },
+	"visibility":     func(r inventoryRecord) string { return r.Visibility },
+	"created_at":     func(r inventoryRecord) string { return formatTime(r.CreatedAt) },
+	"updated_at":     func(r inventoryRecord) string { return formatTime(r.UpdatedAt) },
+	"pushed_at":      func(r inventoryRecord) string { return formatTime(r.PushedAt) },
+}
+
+// csvOutputFormatter writes a spreadsheet of the repositories, one line per
+// repository, once every repository has been handled.
+type csvOutputFormatter struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	columns []string
+	data    []inventoryRecord
+}
+
+func newCsvOutput(file string, columns []string) (*csvOutputFormatter, error) {
+	if len(columns) == 0 {
+		columns = csvDefaultColumns
+	}
+
+	for _, column := range columns {
+		if _, exists := csvColumns[column]; !exists {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	w, err := openOutputFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvOutputFormatter{w: w, columns: columns}, nil
+}
+
+func (o *csvOutputFormatter) Handle(r provider.Repository) (Result, error) {
+	record := newInventoryRecord(r)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.data = append(o.data, record)
+
+	return Result{}, nil
+}
+
+func (o *csvOutputFormatter) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.write(); err != nil {
+		o.w.Close()
+		return err
+	}
+
+	return o.w.Close()
+}
+
+func (o *csvOutputFormatter) write() error {
+	// Repositories are handled concurrently, so they are sorted to keep the
+	// spreadsheet stable from one run to the other.
+	sortInventory(o.data)
+
+	writer := csv.NewWriter(o.w)
+	if err := writer.Write(o.columns); err != nil {
+		return err
+	}
+
+	for _, r := range o.data {
+		line := make([]string, len(o.columns))
+		for i, column := range o.columns {
+			line[i] = csvColumns[column](r)
+		}
+
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
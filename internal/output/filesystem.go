@@ -1,46 +1,252 @@
 package output
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/jdecool/github-vacuum/internal/provider"
 	log "github.com/sirupsen/logrus"
-	gossh "golang.org/x/crypto/ssh"
 )
 
+// pathLocks holds a mutex per local copy, so that a repository listed twice
+// (e.g. by an organization and by one of its members) is not cloned or
+// updated by two workers at once, the second one finding it on disk.
+var pathLocks sync.Map
+
+func lockPath(path string) func() {
+	mu, _ := pathLocks.LoadOrStore(stateKey(path), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+
+	return mu.(*sync.Mutex).Unlock
+}
+
 type filesystemOutputFormatter struct {
-	opts FilesystemOptions
+	opts     FilesystemOptions
+	ssh      *sshCredentials
+	hostKeys *hostKeyVerifier
 }
 
 type FilesystemOptions struct {
-	Folder     string
-	SSHKeyPath string
+	// Context aborts clones and fetches in progress once it is done, partial
+	// clones being removed.
+	Context context.Context
+	Folder  string
+	// SSHKeyPaths are offered in order, before the keys of the SSH agent.
+	// Encrypted keys are decrypted with the passphrase read from
+	// SSHKeyPassphraseFile, or else from the SSHKeyPassphraseEnv variable.
+	SSHKeyPaths          []string
+	SSHKeyPassphraseFile string
+	SSHKeyPassphraseEnv  string
+	// KnownHostsPath defaults to ~/.ssh/known_hosts, HostKeyPolicy to
+	// HOST_KEY_POLICY_STRICT.
+	KnownHostsPath string
+	HostKeyPolicy  string
+	// Sync fetches and fast-forwards repositories already on disk instead
+	// of skipping them.
+	Sync bool
+	// Mirror stores bare mirrors as <owner>/<name>.git, with every ref of
+	// the remote. Existing mirrors are always updated with a pruning fetch.
+	Mirror bool
+	// Depth limits clones, and later fetches of shallow clones, to the given
+	// number of commits.
+	Depth int
+	// SingleBranch only clones the default branch.
+	SingleBranch bool
+	// SkipUnchanged leaves repositories on disk untouched, without any
+	// network round-trip, when the provider tells they were not pushed since
+	// they were last cloned or updated.
+	SkipUnchanged bool
+	// Prune reports the repositories of the folder which are no longer
+	// listed, deleting them or moving them below _archive/<date>/ depending
+	// on PrunePolicy, PRUNE_POLICY_REPORT by default.
+	Prune       bool
+	PrunePolicy string
+	// State skips the repositories on disk which were not pushed since they
+	// were last cloned or updated successfully, as recorded by previous runs.
+	State *State
+
+	// AccessToken authenticates HTTPS clones, along with Username or the
+	// provider's usual username when it is empty.
+	Username    string
+	AccessToken string
 }
 
 func newFilesystemOutput(opts FilesystemOptions) (*filesystemOutputFormatter, error) {
-	return &filesystemOutputFormatter{opts}, nil
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+
+	if opts.Mirror && (opts.Depth > 0 || opts.SingleBranch) {
+		return nil, errors.New("Mirror mode cannot be combined with depth or single branch.")
+	}
+
+	if err := checkPrunePolicy(opts.PrunePolicy); err != nil {
+		return nil, err
+	}
+
+	passphrase, err := readSSHPassphrase(opts.SSHKeyPassphraseFile, opts.SSHKeyPassphraseEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	ssh, err := newSSHCredentials(opts.SSHKeyPaths, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeys, err := newHostKeyVerifier(opts.HostKeyPolicy, opts.KnownHostsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filesystemOutputFormatter{opts, ssh, hostKeys}, nil
 }
 
-func (o filesystemOutputFormatter) Handle(r provider.Repository) {
+func (o filesystemOutputFormatter) Handle(r provider.Repository) (Result, error) {
 	path := o.opts.Folder
 	if strings.TrimSpace(path) != "" {
 		path += "/"
 	}
-	path += r.Owner + "/" + r.Name
+	path += r.RelativePath()
+	if o.opts.Mirror {
+		path += ".git"
+	}
 
-	if err := o.tryClone(r, path, r.SSHUrl, "SSH"); err != nil {
-		if err := o.tryClone(r, path, r.CloneURL, "HTTPS"); err != nil {
-			log.Errorf("Failed to clone repository %s with both SSH and HTTPS: %v", r.Fullname(), err)
-			return
+	defer lockPath(path)()
+
+	result, err := o.process(r, path)
+	if err == nil && !result.Skipped {
+		result.Commit = headCommit(path)
+
+		if o.opts.SkipUnchanged {
+			o.markPushed(r, path)
+		}
+	}
+
+	if err != nil || !result.Skipped {
+		o.opts.State.record(r, path, result.Commit, err)
+	}
+
+	return result, err
+}
+
+func (o filesystemOutputFormatter) process(r provider.Repository, path string) (Result, error) {
+	if _, err := os.Stat(path); err == nil {
+		if o.opts.SkipUnchanged && o.unchanged(r, path) {
+			return skipped("not pushed since the last update of " + path), nil
+		}
+
+		if entry, ok := o.opts.State.unchanged(r, path); ok {
+			return skipped("unchanged since " + entry.ProcessedAt.Format(time.RFC3339)), nil
+		}
+
+		if !o.opts.Mirror && !o.opts.Sync {
+			return skipped("already exists in " + path), nil
+		}
+
+		// The amount of data transferred by a fetch is approximated by the
+		// growth of the objects of the repository.
+		sizeBefore := objectsSize(path)
+		grown := func() int64 {
+			return max(objectsSize(path)-sizeBefore, 0)
+		}
+
+		if o.opts.Mirror {
+			method, err := o.updateMirror(r, path)
+			if err != nil {
+				return Result{Transport: method}, fmt.Errorf("failed to update mirror: %w", err)
+			}
+
+			log.Debugf("Successfully updated mirror of repository %s in %s", r.Fullname(), path)
+			return Result{Transport: method, Bytes: grown()}, nil
+		}
+
+		method, err := o.sync(r, path)
+		if err != nil {
+			if errors.Is(err, errDiverged) || errors.Is(err, errLocalChanges) {
+				return Result{Skipped: true, Reason: "not updated: " + err.Error(), Transport: method, Bytes: grown()}, nil
+			}
+
+			return Result{Transport: method}, fmt.Errorf("failed to update: %w", err)
+		}
+
+		log.Debugf("Successfully updated repository %s in %s", r.Fullname(), path)
+		return Result{Transport: method, Bytes: grown()}, nil
+	}
+
+	method := "SSH"
+	if err := o.tryClone(r, path, r.SSHUrl, method); err != nil {
+		var hostKeyErr *HostKeyError
+		if errors.As(err, &hostKeyErr) || o.opts.Context.Err() != nil {
+			return Result{Transport: method}, fmt.Errorf("failed to clone: %w", err)
+		}
+
+		method = "HTTPS"
+		if err := o.tryClone(r, path, r.CloneURL, method); err != nil {
+			return Result{}, fmt.Errorf("failed to clone with both SSH and HTTPS: %w", err)
 		}
 	}
 
 	log.Debugf("Successfully cloned repository %s to %s", r.Fullname(), path)
+	return Result{Transport: method, Bytes: objectsSize(path)}, nil
+}
+
+// objectsSize returns the size of the objects of the repository at path,
+// which leaves its working tree out.
+func objectsSize(path string) int64 {
+	if info, err := os.Stat(filepath.Join(path, ".git")); err == nil && info.IsDir() {
+		return dirSize(filepath.Join(path, ".git", "objects"))
+	}
+
+	return dirSize(filepath.Join(path, "objects"))
+}
+
+// dirSize returns the size of the files below path, zero when it does not
+// exist.
+func dirSize(path string) int64 {
+	var size int64
+
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// headCommit returns the SHA of the HEAD of the repository at path, or an
+// empty string when it has none (e.g. an empty repository).
+func headCommit(path string) string {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return ""
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+
+	return head.Hash().String()
 }
 
 func (o filesystemOutputFormatter) tryClone(r provider.Repository, path, url, method string) error {
@@ -51,20 +257,24 @@ func (o filesystemOutputFormatter) tryClone(r provider.Repository, path, url, me
 	log.Debugf("Attempting to clone %s using %s: %s", r.Fullname(), method, url)
 
 	cloneOptions := &git.CloneOptions{
-		URL: url,
+		URL:          url,
+		Mirror:       o.opts.Mirror,
+		Depth:        o.opts.Depth,
+		SingleBranch: o.opts.SingleBranch,
 	}
 
-	if method == "SSH" && strings.TrimSpace(o.opts.SSHKeyPath) != "" {
-		auth, err := o.createSSHAuth()
-		if err != nil {
-			log.Debugf("Failed to create SSH auth for %s: %v", r.Fullname(), err)
-			return err
-		}
-		cloneOptions.Auth = auth
-		log.Debugf("Using SSH key from %s for %s", o.opts.SSHKeyPath, r.Fullname())
+	if o.opts.SingleBranch && strings.TrimSpace(r.DefaultBranch) != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(r.DefaultBranch)
+	}
+
+	auth, err := o.createAuth(r, method, url)
+	if err != nil {
+		log.Debugf("Failed to create %s auth for %s: %v", method, r.Fullname(), err)
+		return err
 	}
+	cloneOptions.Auth = auth
 
-	_, err := git.PlainClone(path, false, cloneOptions)
+	_, err = git.PlainCloneContext(o.opts.Context, path, false, cloneOptions)
 
 	if err != nil {
 		if method == "SSH" && isSSHAuthError(err) {
@@ -80,18 +290,47 @@ func (o filesystemOutputFormatter) tryClone(r provider.Repository, path, url, me
 	return nil
 }
 
-func (o filesystemOutputFormatter) createSSHAuth() (transport.AuthMethod, error) {
-	privateKey, err := os.ReadFile(o.opts.SSHKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SSH key file %s: %w", o.opts.SSHKeyPath, err)
+func (o filesystemOutputFormatter) createAuth(r provider.Repository, method, url string) (transport.AuthMethod, error) {
+	if method == "SSH" {
+		return o.createSSHAuth(url)
 	}
 
-	auth, err := ssh.NewPublicKeys("git", privateKey, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create SSH auth from key %s: %w", o.opts.SSHKeyPath, err)
+	if method == "HTTPS" && strings.TrimSpace(o.opts.AccessToken) != "" {
+		return o.createHTTPSAuth(r), nil
 	}
 
-	auth.HostKeyCallback = gossh.InsecureIgnoreHostKey()
+	return nil, nil
+}
+
+func (o filesystemOutputFormatter) createHTTPSAuth(r provider.Repository) transport.AuthMethod {
+	username := o.opts.Username
+	if strings.TrimSpace(username) == "" && r.Provider != nil {
+		username = provider.HTTPSUsername(r.Provider.GetName())
+	}
+
+	log.Debugf("Using access token as %s for %s", username, r.Fullname())
+
+	return &http.BasicAuth{
+		Username: username,
+		Password: o.opts.AccessToken,
+	}
+}
+
+func (o filesystemOutputFormatter) createSSHAuth(url string) (transport.AuthMethod, error) {
+	if !o.ssh.available() {
+		return nil, errors.New("no SSH key nor SSH agent available")
+	}
+
+	user := "git"
+	if endpoint, err := transport.NewEndpoint(url); err == nil && endpoint.User != "" {
+		user = endpoint.User
+	}
+
+	auth := &ssh.PublicKeysCallback{
+		User:     user,
+		Callback: o.ssh.signers,
+	}
+	o.hostKeys.configure(&auth.HostKeyCallbackHelper, url)
 
 	return auth, nil
 }
@@ -105,5 +344,5 @@ func isSSHAuthError(err error) bool {
 }
 
 func (o filesystemOutputFormatter) Flush() error {
-	return nil
+	return o.opts.State.save()
 }
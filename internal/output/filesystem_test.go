@@ -0,0 +1,88 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jdecool/github-vacuum/internal/provider"
+)
+
+// newSourceRepository creates a repository with a single commit, cloned
+// through its path.
+func newSourceRepository(t *testing.T) string {
+	t.Helper()
+
+	path := t.TempDir()
+
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, "README.md"), []byte("# repo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wt.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := wt.Commit("Initial commit", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// The same repository may be listed twice, e.g. by an organization and by one
+// of its members, and reach two workers at once.
+func TestFilesystemHandlesTheSameRepositoryOnceAtATime(t *testing.T) {
+	withoutAgent(t)
+
+	source := newSourceRepository(t)
+	folder := t.TempDir()
+	path := filepath.Join(folder, "acme", "repo")
+
+	o, err := newFilesystemOutput(FilesystemOptions{Folder: folder})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := provider.Repository{Owner: "acme", Name: "repo", CloneURL: source}
+
+	unlock := lockPath(path)
+
+	done := make(chan error)
+	go func() {
+		_, err := o.Handle(r)
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("repository should not be handled while its path is locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assertExists(t, path, false)
+
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("repository should be cloned once its path is unlocked: %v", err)
+	}
+	assertExists(t, filepath.Join(path, "README.md"), true)
+
+	result, err := o.Handle(r)
+	if err != nil || !result.Skipped {
+		t.Errorf("repository handled again should be skipped, got %+v, %v", result, err)
+	}
+}
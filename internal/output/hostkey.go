@@ -0,0 +1,137 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/knownhosts"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	HOST_KEY_POLICY_STRICT   = "strict"
+	HOST_KEY_POLICY_TOFU     = "tofu"
+	HOST_KEY_POLICY_INSECURE = "insecure"
+)
+
+// HostKeyError reports an SSH server whose host key is unknown, revoked or
+// does not match the known_hosts file. Unlike authentication failures, it
+// never falls back to HTTPS.
+type HostKeyError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("host key verification failed for %s: %v", e.Host, e.Err)
+}
+
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+// hostKeyVerifier checks SSH host keys against a known_hosts file. In
+// trust-on-first-use mode, keys of unknown hosts are appended to the file.
+type hostKeyVerifier struct {
+	policy string
+	path   string
+	mu     sync.Mutex
+}
+
+func newHostKeyVerifier(policy string, path string) (*hostKeyVerifier, error) {
+	switch policy {
+	case "":
+		policy = HOST_KEY_POLICY_STRICT
+	case HOST_KEY_POLICY_STRICT, HOST_KEY_POLICY_TOFU:
+	case HOST_KEY_POLICY_INSECURE:
+		log.Warn("SSH host keys are not verified, connections are exposed to man-in-the-middle attacks")
+	default:
+		return nil, errors.New("Unknown SSH host key policy.")
+	}
+
+	if strings.TrimSpace(path) == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate known_hosts file: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	return &hostKeyVerifier{policy: policy, path: path}, nil
+}
+
+// configure sets the host key callback of auth, and the host key algorithms
+// matching the keys already known for the server of url.
+func (v *hostKeyVerifier) configure(helper *ssh.HostKeyCallbackHelper, url string) {
+	if v.policy == HOST_KEY_POLICY_INSECURE {
+		helper.HostKeyCallback = gossh.InsecureIgnoreHostKey()
+		return
+	}
+
+	helper.HostKeyCallback = v.verify
+
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if db, err := knownhosts.NewDB(v.path); err == nil {
+		helper.HostKeyAlgorithms = db.HostKeyAlgorithms(net.JoinHostPort(endpoint.Host, fmt.Sprint(endpoint.Port)))
+	}
+}
+
+func (v *hostKeyVerifier) verify(hostname string, remote net.Addr, key gossh.PublicKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := knownhosts.NewDB(v.path)
+	if err != nil {
+		if !os.IsNotExist(err) || v.policy != HOST_KEY_POLICY_TOFU {
+			return &HostKeyError{hostname, err}
+		}
+
+		return v.trust(hostname, remote, key)
+	}
+
+	err = db.HostKeyCallback()(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+
+	if knownhosts.IsHostUnknown(err) && v.policy == HOST_KEY_POLICY_TOFU {
+		return v.trust(hostname, remote, key)
+	}
+
+	return &HostKeyError{hostname, err}
+}
+
+func (v *hostKeyVerifier) trust(hostname string, remote net.Addr, key gossh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return &HostKeyError{hostname, err}
+	}
+
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return &HostKeyError{hostname, err}
+	}
+	defer f.Close()
+
+	if err := knownhosts.WriteKnownHost(f, hostname, remote, key); err != nil {
+		return &HostKeyError{hostname, err}
+	}
+
+	log.Infof("Recorded %s host key of %s in %s", key.Type(), hostname, v.path)
+
+	return nil
+}
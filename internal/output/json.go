@@ -0,0 +1,159 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+)
+
+// jsonOutputFormatter writes an inventory of the repositories, either as a
+// single JSON array once every repository has been handled, or streamed as
+// one JSON object per line (NDJSON).
+type jsonOutputFormatter struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	stream bool
+	data   []inventoryRecord
+}
+
+// inventoryRecord uses the keys read by the file provider, so that an
+// inventory can be replayed later on.
+type inventoryRecord struct {
+	Provider      string     `json:"provider,omitempty"`
+	Owner         string     `json:"owner"`
+	Path          string     `json:"path"`
+	Name          string     `json:"name"`
+	CloneURL      string     `json:"clone_url"`
+	SSHUrl        string     `json:"ssh_url"`
+	DefaultBranch string     `json:"default_branch"`
+	Description   string     `json:"description,omitempty"`
+	Topics        []string   `json:"topics,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	Size          int64      `json:"size,omitempty"`
+	Stars         int        `json:"stars"`
+	Fork          bool       `json:"fork"`
+	Archived      bool       `json:"archived"`
+	Template      bool       `json:"template"`
+	Visibility    string     `json:"visibility,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	PushedAt      *time.Time `json:"pushed_at,omitempty"`
+}
+
+func newJsonOutput(file string, stream bool) (*jsonOutputFormatter, error) {
+	w, err := openOutputFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonOutputFormatter{w: w, stream: stream}, nil
+}
+
+// openOutputFile creates the given file, or returns the standard output when
+// no file is given.
+func openOutputFile(file string) (io.WriteCloser, error) {
+	if strings.TrimSpace(file) == "" || file == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	return os.Create(file)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func (o *jsonOutputFormatter) Handle(r provider.Repository) (Result, error) {
+	record := newInventoryRecord(r)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.stream {
+		o.data = append(o.data, record)
+		return Result{}, nil
+	}
+
+	return Result{}, json.NewEncoder(o.w).Encode(record)
+}
+
+func (o *jsonOutputFormatter) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.stream {
+		// Repositories are handled concurrently, so they are sorted to keep
+		// the inventory stable from one run to the other.
+		sortInventory(o.data)
+
+		if o.data == nil {
+			o.data = []inventoryRecord{}
+		}
+
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(o.data); err != nil {
+			o.w.Close()
+			return err
+		}
+	}
+
+	return o.w.Close()
+}
+
+func sortInventory(records []inventoryRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Provider != records[j].Provider {
+			return records[i].Provider < records[j].Provider
+		}
+
+		return records[i].Path < records[j].Path
+	})
+}
+
+func newInventoryRecord(r provider.Repository) inventoryRecord {
+	record := inventoryRecord{
+		Owner:         r.Owner,
+		Path:          r.Path,
+		Name:          r.Name,
+		CloneURL:      r.CloneURL,
+		SSHUrl:        r.SSHUrl,
+		DefaultBranch: r.DefaultBranch,
+		Description:   r.Description,
+		Topics:        r.Topics,
+		Language:      r.Language,
+		Size:          r.Size,
+		Stars:         r.Stars,
+		Fork:          r.Fork,
+		Archived:      r.Archived,
+		Template:      r.Template,
+		Visibility:    r.Visibility,
+		CreatedAt:     timeOrNil(r.CreatedAt),
+		UpdatedAt:     timeOrNil(r.UpdatedAt),
+		PushedAt:      timeOrNil(r.PushedAt),
+	}
+
+	if r.Provider != nil {
+		record.Provider = r.Provider.GetName()
+	}
+
+	return record
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
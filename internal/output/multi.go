@@ -0,0 +1,95 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+)
+
+// multiOutput hands every repository over to several outputs, in order.
+type multiOutput []Output
+
+// NewMultiOutput returns an output forwarding repositories to each of the
+// given outputs, so that a single listing feeds all of them.
+func NewMultiOutput(outputs ...Output) Output {
+	return multiOutput(outputs)
+}
+
+// Handle hands the repository over to every output, even when some of them
+// fail. It is only reported as skipped when every output skipped it.
+func (o multiOutput) Handle(repo provider.Repository) (Result, error) {
+	var errorList error
+	var reasons []string
+	var r Result
+
+	for _, child := range o {
+		result, err := child.Handle(repo)
+
+		if r.Transport == "" {
+			r.Transport = result.Transport
+		}
+		r.Bytes += result.Bytes
+		if r.Commit == "" {
+			r.Commit = result.Commit
+		}
+
+		if err != nil {
+			errorList = appendError(errorList, err)
+			continue
+		}
+
+		if result.Skipped {
+			reasons = append(reasons, result.Reason)
+		}
+	}
+
+	if errorList != nil {
+		return r, errorList
+	}
+
+	if len(reasons) == len(o) {
+		r.Skipped = true
+		r.Reason = strings.Join(reasons, "; ")
+	}
+
+	return r, nil
+}
+
+// Prune forwards the listing to every output able to prune, even when some of
+// them fail.
+func (o multiOutput) Prune(owner string, listed []provider.Repository) error {
+	var errorList error
+
+	for _, child := range o {
+		if pruner, ok := child.(Pruner); ok {
+			if err := pruner.Prune(owner, listed); err != nil {
+				errorList = appendError(errorList, err)
+			}
+		}
+	}
+
+	return errorList
+}
+
+// Flush flushes every output, even when some of them fail.
+func (o multiOutput) Flush() error {
+	var errorList error
+
+	for _, child := range o {
+		if err := child.Flush(); err != nil {
+			errorList = appendError(errorList, err)
+		}
+	}
+
+	return errorList
+}
+
+func appendError(errorList error, err error) error {
+	if errorList == nil {
+		return errors.New(err.Error())
+	}
+
+	return fmt.Errorf("%w; %s", errorList, err.Error())
+}
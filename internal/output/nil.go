@@ -12,8 +12,10 @@ func newNilOutput() (*nilOutputFormatter, error) {
 	return &nilOutputFormatter{}, nil
 }
 
-func (o nilOutputFormatter) Handle(r provider.Repository) {
+func (o nilOutputFormatter) Handle(r provider.Repository) (Result, error) {
 	log.Infof("Processing repository: %s", r.Fullname())
+
+	return Result{}, nil
 }
 
 func (o nilOutputFormatter) Flush() error {
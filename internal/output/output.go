@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"errors"
 
 	"github.com/jdecool/github-vacuum/internal/provider"
@@ -10,29 +11,104 @@ const (
 	OUTPUT_FILESYSTEM = "filesystem"
 	OUTPUT_NIL        = "nil"
 	OUTPUT_REPO       = "repo"
+	OUTPUT_JSON       = "json"
+	OUTPUT_NDJSON     = "ndjson"
+	OUTPUT_CSV        = "csv"
+	OUTPUT_SQLITE     = "sqlite"
 )
 
+// Output receives every repository found by the provider. Handle may be
+// called from several goroutines at once; it returns an error when the
+// repository could not be processed.
 type Output interface {
-	Handle(r provider.Repository)
+	Handle(r provider.Repository) (Result, error)
 	Flush() error
 }
 
+// Pruner is implemented by the outputs keeping repositories from one run to
+// the other, which can then get rid of the ones the provider no longer lists.
+type Pruner interface {
+	// Prune is given every repository listed for an owner, filtered out ones
+	// included, once the listing completed successfully.
+	Prune(owner string, listed []provider.Repository) error
+}
+
+// Result tells what an output did with a repository it processed.
+type Result struct {
+	// Skipped repositories were left untouched, for the given Reason.
+	Skipped bool
+	Reason  string
+	// Transport is the protocol used to reach the repository, "SSH" or
+	// "HTTPS", when the output transferred it.
+	Transport string
+	// Bytes is the approximate amount of data transferred for the repository,
+	// measured on its objects rather than on its working tree.
+	Bytes int64
+	// Commit is the SHA of the HEAD reached by the local copy.
+	Commit string
+}
+
+func skipped(reason string) Result {
+	return Result{Skipped: true, Reason: reason}
+}
+
 type OutputOptions struct {
-	Folder     string
-	SSHKeyPath string
+	Context              context.Context
+	Target               string
+	File                 string
+	Columns              []string
+	Folder               string
+	SSHKeyPaths          []string
+	SSHKeyPassphraseFile string
+	SSHKeyPassphraseEnv  string
+	KnownHostsPath       string
+	HostKeyPolicy        string
+	Sync                 bool
+	Mirror               bool
+	Depth                int
+	SingleBranch         bool
+	SkipUnchanged        bool
+	Prune                bool
+	PrunePolicy          string
+	State                *State
+	Username             string
+	AccessToken          string
 }
 
 func NewOutput(format string, options OutputOptions) (Output, error) {
 	switch format {
 	case OUTPUT_FILESYSTEM:
 		return newFilesystemOutput(FilesystemOptions{
-			Folder:     options.Folder,
-			SSHKeyPath: options.SSHKeyPath,
+			Context:              options.Context,
+			Folder:               options.Folder,
+			SSHKeyPaths:          options.SSHKeyPaths,
+			SSHKeyPassphraseFile: options.SSHKeyPassphraseFile,
+			SSHKeyPassphraseEnv:  options.SSHKeyPassphraseEnv,
+			KnownHostsPath:       options.KnownHostsPath,
+			HostKeyPolicy:        options.HostKeyPolicy,
+			Sync:                 options.Sync,
+			Mirror:               options.Mirror,
+			Depth:                options.Depth,
+			SingleBranch:         options.SingleBranch,
+			SkipUnchanged:        options.SkipUnchanged,
+			Prune:                options.Prune,
+			PrunePolicy:          options.PrunePolicy,
+			State:                options.State,
+			Username:             options.Username,
+			AccessToken:          options.AccessToken,
 		})
 	case OUTPUT_NIL:
 		return newNilOutput()
 	case OUTPUT_REPO:
-		return newRepoOutput()
+		return newRepoOutput(options.File)
+	case OUTPUT_JSON:
+		return newJsonOutput(options.File, false)
+	case OUTPUT_NDJSON:
+		return newJsonOutput(options.File, true)
+	case OUTPUT_CSV:
+		return newCsvOutput(options.File, options.Columns)
+	case OUTPUT_SQLITE:
+		return newSqliteOutput(options.File, options.Target)
 	default:
 		return nil, errors.New("Unknown output format.")
 	}
@@ -0,0 +1,191 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PRUNE_POLICY_REPORT  = "report"
+	PRUNE_POLICY_DELETE  = "delete"
+	PRUNE_POLICY_ARCHIVE = "archive"
+)
+
+// pruneArchiveFolder is the folder of the output folder in which archived
+// repositories are moved, below a folder named after the day of the run.
+const pruneArchiveFolder = "_archive"
+
+func checkPrunePolicy(policy string) error {
+	switch policy {
+	case "", PRUNE_POLICY_REPORT, PRUNE_POLICY_DELETE, PRUNE_POLICY_ARCHIVE:
+		return nil
+	default:
+		return errors.New("Unknown prune policy.")
+	}
+}
+
+// Prune looks for the repositories stored below the folder of the owner which
+// are not listed anymore, e.g. deleted, renamed or transferred ones, and
+// reports, deletes or archives them according to the prune policy.
+func (o filesystemOutputFormatter) Prune(owner string, listed []provider.Repository) error {
+	if !o.opts.Prune {
+		return nil
+	}
+
+	// Only the folder of the owner is walked, as the listing of a user may
+	// contain repositories of other owners (e.g. of the organizations they
+	// are a member of) whose other repositories are not listed. It is matched
+	// regardless of case, the listed owner possibly differing in case from
+	// the folder its repositories are stored in (e.g. Bitbucket Data Center
+	// project keys, or a username typed differently from its login). Paths
+	// are compared case-folded too, so that a listed repository is never
+	// taken for an orphan on a case-insensitive filesystem. Both layouts are
+	// known, so that clones made before switching to mirrors, or the other
+	// way around, are not taken for orphans either.
+	known := map[string]bool{}
+	for _, r := range listed {
+		path := filepath.Join(o.opts.Folder, r.RelativePath())
+		known[foldPath(path)] = true
+		known[foldPath(path+".git")] = true
+	}
+
+	roots, err := ownerFolders(o.opts.Folder, owner)
+	if err != nil {
+		return fmt.Errorf("failed to prune %s: %w", owner, err)
+	}
+
+	var orphans []string
+	for _, root := range roots {
+		found, err := findOrphans(root, known)
+		if err != nil {
+			return fmt.Errorf("failed to prune %s: %w", owner, err)
+		}
+
+		orphans = append(orphans, found...)
+	}
+
+	var errorList error
+	for _, path := range orphans {
+		if err := o.pruneOrphan(path); err != nil {
+			log.Errorf("Failed to prune %s: %v", path, err)
+			errorList = appendError(errorList, err)
+		}
+	}
+
+	return errorList
+}
+
+func foldPath(path string) string {
+	return strings.ToLower(path)
+}
+
+// ownerFolders returns the folders below folder matching owner regardless of
+// case, one segment at a time for owners made of several (e.g. GitLab
+// subgroups).
+func ownerFolders(folder, owner string) ([]string, error) {
+	folders := []string{folder}
+
+	for _, segment := range strings.Split(owner, "/") {
+		var matches []string
+
+		for _, parent := range folders {
+			dir := parent
+			if dir == "" {
+				dir = "."
+			}
+
+			entries, err := os.ReadDir(dir)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range entries {
+				if e.IsDir() && strings.EqualFold(e.Name(), segment) {
+					matches = append(matches, filepath.Join(parent, e.Name()))
+				}
+			}
+		}
+
+		folders = matches
+	}
+
+	return folders, nil
+}
+
+// findOrphans returns the repositories found below root which are not known.
+// Known repositories are not walked into.
+func findOrphans(root string, known map[string]bool) ([]string, error) {
+	var orphans []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == root {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() || path == root {
+			return nil
+		}
+
+		if known[foldPath(path)] {
+			return fs.SkipDir
+		}
+
+		if provider.IsRepository(path) {
+			orphans = append(orphans, path)
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+
+	return orphans, err
+}
+
+func (o filesystemOutputFormatter) pruneOrphan(path string) error {
+	switch o.opts.PrunePolicy {
+	case PRUNE_POLICY_DELETE:
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+
+		log.Warnf("Deleted %s, not listed by the provider anymore", path)
+	case PRUNE_POLICY_ARCHIVE:
+		rel, err := filepath.Rel(o.opts.Folder, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(o.opts.Folder, pruneArchiveFolder, time.Now().Format(time.DateOnly), rel)
+		if _, err := os.Stat(target); err == nil {
+			return fmt.Errorf("%s already exists", target)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		if err := os.Rename(path, target); err != nil {
+			return err
+		}
+
+		log.Warnf("Archived %s to %s, not listed by the provider anymore", path, target)
+	default:
+		log.Warnf("Found %s, not listed by the provider anymore", path)
+	}
+
+	return nil
+}
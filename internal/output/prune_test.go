@@ -0,0 +1,195 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+)
+
+// makeClone creates a folder which looks like a working tree to
+// provider.IsRepository.
+func makeClone(t *testing.T, path string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Join(path, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// makeMirror creates a folder which looks like a bare repository to
+// provider.IsRepository.
+func makeMirror(t *testing.T, path string) {
+	t.Helper()
+
+	for _, dir := range []string{"objects", "refs"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(path, "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newPruningOutput(folder, policy string) filesystemOutputFormatter {
+	return filesystemOutputFormatter{opts: FilesystemOptions{
+		Folder:      folder,
+		Prune:       true,
+		PrunePolicy: policy,
+	}}
+}
+
+func assertExists(t *testing.T, path string, exists bool) {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if exists && err != nil {
+		t.Errorf("%s should exist: %v", path, err)
+	}
+	if !exists && err == nil {
+		t.Errorf("%s should not exist", path)
+	}
+}
+
+func TestPruneDeletesOrphans(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "acme", "kept"))
+	makeMirror(t, filepath.Join(folder, "acme", "mirrored.git"))
+	makeClone(t, filepath.Join(folder, "acme", "gone"))
+	makeClone(t, filepath.Join(folder, "acme", "nested", "gone"))
+	makeClone(t, filepath.Join(folder, "other", "untouched"))
+
+	listed := []provider.Repository{
+		{Owner: "acme", Name: "kept", Path: "acme/kept"},
+		{Owner: "acme", Name: "mirrored", Path: "acme/mirrored"},
+	}
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("acme", listed); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "acme", "kept"), true)
+	assertExists(t, filepath.Join(folder, "acme", "mirrored.git"), true)
+	assertExists(t, filepath.Join(folder, "acme", "gone"), false)
+	assertExists(t, filepath.Join(folder, "acme", "nested", "gone"), false)
+	assertExists(t, filepath.Join(folder, "other", "untouched"), true)
+}
+
+// The listed owner may differ in case from the folder its repositories are
+// stored in, as Bitbucket Data Center lists projects by their upper case key
+// while repositories are stored below their lower case one.
+func TestPruneWalksTheOwnerFolderRegardlessOfCase(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "proj", "kept"))
+	makeClone(t, filepath.Join(folder, "proj", "gone"))
+
+	listed := []provider.Repository{
+		{Owner: "proj", Name: "kept", Path: "proj/kept"},
+	}
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("PROJ", listed); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "proj", "kept"), true)
+	assertExists(t, filepath.Join(folder, "proj", "gone"), false)
+}
+
+// The listing of a user may contain repositories of an organization they are
+// a member of, the other repositories of which must not be taken for orphans.
+func TestPruneOnlyWalksTheOwnerFolder(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "alice", "kept"))
+	makeClone(t, filepath.Join(folder, "alice", "gone"))
+	makeClone(t, filepath.Join(folder, "acme", "api"))
+	makeClone(t, filepath.Join(folder, "acme", "web"))
+
+	listed := []provider.Repository{
+		{Owner: "alice", Name: "kept", Path: "alice/kept"},
+		{Owner: "acme", Name: "web", Path: "acme/web"},
+	}
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("alice", listed); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "alice", "kept"), true)
+	assertExists(t, filepath.Join(folder, "alice", "gone"), false)
+	assertExists(t, filepath.Join(folder, "acme", "api"), true)
+	assertExists(t, filepath.Join(folder, "acme", "web"), true)
+}
+
+func TestPruneWalksNestedOwnerFolders(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "group", "sub", "kept"))
+	makeClone(t, filepath.Join(folder, "group", "sub", "gone"))
+	makeClone(t, filepath.Join(folder, "group", "other"))
+
+	listed := []provider.Repository{
+		{Owner: "group/sub", Name: "kept", Path: "group/sub/kept"},
+	}
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("Group/Sub", listed); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "group", "sub", "kept"), true)
+	assertExists(t, filepath.Join(folder, "group", "sub", "gone"), false)
+	assertExists(t, filepath.Join(folder, "group", "other"), true)
+}
+
+// Clones made by an earlier run keep their case when the repository gets
+// renamed to another case, which case-insensitive filesystems do not tell
+// apart.
+func TestPruneComparesPathsRegardlessOfCase(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "jdoe", "api"))
+
+	listed := []provider.Repository{
+		{Owner: "jdoe", Name: "API", Path: "jdoe/API"},
+	}
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("JDoe", listed); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "jdoe", "api"), true)
+}
+
+func TestPruneWalksTheOwnerFolderWhenNothingIsListed(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "acme", "gone"))
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_DELETE).Prune("acme", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "acme", "gone"), false)
+}
+
+func TestPruneArchivesOrphans(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "acme", "gone"))
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_ARCHIVE).Prune("acme", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "acme", "gone"), false)
+	assertExists(t, filepath.Join(folder, pruneArchiveFolder, time.Now().Format(time.DateOnly), "acme", "gone"), true)
+}
+
+func TestPruneReportsOrphans(t *testing.T) {
+	folder := t.TempDir()
+	makeClone(t, filepath.Join(folder, "acme", "gone"))
+
+	if err := newPruningOutput(folder, PRUNE_POLICY_REPORT).Prune("acme", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExists(t, filepath.Join(folder, "acme", "gone"), true)
+}
@@ -3,13 +3,17 @@ package output
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
+	"io"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/jdecool/github-vacuum/internal/provider"
 )
 
 type repoOuputFormatter struct {
+	mu               sync.Mutex
+	w                io.WriteCloser
 	processedRemotes map[string]manifestRemote
 	data             manifest
 }
@@ -30,21 +34,37 @@ type manifestProject struct {
 	Revision string `xml:"revision,attr"`
 }
 
-func newRepoOutput() (*repoOuputFormatter, error) {
+func newRepoOutput(file string) (*repoOuputFormatter, error) {
+	w, err := openOutputFile(file)
+	if err != nil {
+		return nil, err
+	}
+
 	return &repoOuputFormatter{
+		w:                w,
 		processedRemotes: map[string]manifestRemote{},
 		data:             manifest{},
 	}, nil
 }
 
-func (o *repoOuputFormatter) Handle(repo provider.Repository) {
+func (o *repoOuputFormatter) Handle(repo provider.Repository) (Result, error) {
+	if !strings.Contains(repo.Path, "/") {
+		return Result{}, fmt.Errorf("path %s has no namespace", repo.Path)
+	}
 	remoteName := repo.Path[0:strings.Index(repo.Path, "/")]
+
+	if !strings.Contains(repo.SSHUrl, remoteName) {
+		return Result{}, fmt.Errorf("SSH URL %q does not contain namespace %s", repo.SSHUrl, remoteName)
+	}
 	remoteUrl := repo.SSHUrl[0:strings.Index(repo.SSHUrl, remoteName)] + remoteName
 	if !strings.HasPrefix(remoteUrl, "ssh://") {
 		remoteUrl = strings.Replace(remoteUrl, ":", "/", 1)
 		remoteUrl = "ssh://" + remoteUrl
 	}
 
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
 	remote, exists := o.processedRemotes[remoteName]
 	if !exists {
 		remote = o.data.AddRemote(repo.Provider, remoteName, remoteUrl)
@@ -52,17 +72,35 @@ func (o *repoOuputFormatter) Handle(repo provider.Repository) {
 	}
 
 	o.data.AddProject(remote, repo)
+
+	return Result{}, nil
 }
 
-func (o repoOuputFormatter) Flush() error {
+func (o *repoOuputFormatter) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Repositories are handled concurrently, so they are sorted to keep the
+	// manifest stable from one run to the other.
+	sort.SliceStable(o.data.Remotes, func(i, j int) bool {
+		return o.data.Remotes[i].Name < o.data.Remotes[j].Name
+	})
+	sort.SliceStable(o.data.Projects, func(i, j int) bool {
+		return o.data.Projects[i].Name < o.data.Projects[j].Name
+	})
+
 	xml, err := xml.MarshalIndent(o.data, " ", "  ")
 	if err != nil {
+		o.w.Close()
 		return err
 	}
 
-	fmt.Fprintf(os.Stdout, "%s\n", xml)
+	if _, err := fmt.Fprintf(o.w, "%s\n", xml); err != nil {
+		o.w.Close()
+		return err
+	}
 
-	return nil
+	return o.w.Close()
 }
 
 func (m *manifest) AddRemote(p provider.Provider, name string, url string) manifestRemote {
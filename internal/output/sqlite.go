@@ -0,0 +1,137 @@
+package output
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS repositories (
+	target         TEXT NOT NULL,
+	provider       TEXT NOT NULL,
+	path           TEXT NOT NULL,
+	owner          TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	clone_url      TEXT,
+	ssh_url        TEXT,
+	default_branch TEXT,
+	description    TEXT,
+	topics         TEXT,
+	language       TEXT,
+	size           INTEGER,
+	stars          INTEGER,
+	fork           INTEGER,
+	archived       INTEGER,
+	template       INTEGER,
+	visibility     TEXT,
+	created_at     TEXT,
+	updated_at     TEXT,
+	pushed_at      TEXT,
+	first_seen     TEXT NOT NULL,
+	last_seen      TEXT NOT NULL,
+	PRIMARY KEY (target, path)
+)`
+
+// sqliteBusyTimeout is how long a write waits for the database to be unlocked.
+const sqliteBusyTimeout = 30 * time.Second
+
+// Repositories keep the time they were first seen, every other column is
+// refreshed.
+const sqliteUpsert = `
+INSERT INTO repositories (
+	target, provider, path, owner, name, clone_url, ssh_url, default_branch,
+	description, topics, language, size, stars, fork, archived, template,
+	visibility, created_at, updated_at, pushed_at, first_seen, last_seen
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (target, path) DO UPDATE SET
+	provider = excluded.provider,
+	owner = excluded.owner,
+	name = excluded.name,
+	clone_url = excluded.clone_url,
+	ssh_url = excluded.ssh_url,
+	default_branch = excluded.default_branch,
+	description = excluded.description,
+	topics = excluded.topics,
+	language = excluded.language,
+	size = excluded.size,
+	stars = excluded.stars,
+	fork = excluded.fork,
+	archived = excluded.archived,
+	template = excluded.template,
+	visibility = excluded.visibility,
+	created_at = excluded.created_at,
+	updated_at = excluded.updated_at,
+	pushed_at = excluded.pushed_at,
+	last_seen = excluded.last_seen`
+
+// sqliteOutputFormatter upserts the repositories into a SQLite database,
+// keyed by target and path. The target is the label of the provider, so that
+// several instances of the same provider type (e.g. github.com and a GitHub
+// Enterprise server) do not overwrite each other. Every repository found by
+// a run gets the same last-seen time, so that the repositories which
+// disappeared since are the ones last seen before the latest run.
+type sqliteOutputFormatter struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	target string
+	seen   string
+}
+
+func newSqliteOutput(file string, target string) (*sqliteOutputFormatter, error) {
+	if strings.TrimSpace(file) == "" {
+		return nil, errors.New("SQLite output requires an output file.")
+	}
+
+	// Targets sharing a database write to it concurrently, each one waiting
+	// for the lock of the other instead of failing with SQLITE_BUSY.
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_busy_timeout=%d", file, sqliteBusyTimeout.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteOutputFormatter{
+		db:     db,
+		target: target,
+		seen:   time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (o *sqliteOutputFormatter) Handle(r provider.Repository) (Result, error) {
+	record := newInventoryRecord(r)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, err := o.db.Exec(sqliteUpsert,
+		o.target, record.Provider, record.Path, record.Owner, record.Name,
+		record.CloneURL, record.SSHUrl, record.DefaultBranch,
+		record.Description, strings.Join(record.Topics, ","), record.Language,
+		record.Size, record.Stars, record.Fork, record.Archived, record.Template,
+		record.Visibility, formatTime(record.CreatedAt), formatTime(record.UpdatedAt), formatTime(record.PushedAt),
+		o.seen, o.seen,
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to store repository: %w", err)
+	}
+
+	return Result{}, nil
+}
+
+func (o *sqliteOutputFormatter) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.db.Close()
+}
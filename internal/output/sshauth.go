@@ -0,0 +1,110 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	sshagent "github.com/xanzy/ssh-agent"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshCredentials holds the keys offered to SSH servers: the private keys
+// given by the user, in order, followed by the keys of the SSH agent.
+type sshCredentials struct {
+	keys  []gossh.Signer
+	agent agent.Agent
+}
+
+func newSSHCredentials(keyPaths []string, passphrase string) (*sshCredentials, error) {
+	c := &sshCredentials{}
+
+	for _, path := range keyPaths {
+		signer, err := loadSSHKey(path, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		c.keys = append(c.keys, signer)
+	}
+
+	if sshagent.Available() {
+		// The connection stays open for the whole run, it is shared by every
+		// clone.
+		a, _, err := sshagent.New()
+		if err != nil {
+			log.Warnf("Failed to connect to the SSH agent: %v", err)
+		} else {
+			c.agent = a
+		}
+	}
+
+	return c, nil
+}
+
+func loadSSHKey(path string, passphrase string) (gossh.Signer, error) {
+	privateKey, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key file %s: %w", path, err)
+	}
+
+	signer, err := gossh.ParsePrivateKey(privateKey)
+
+	var missingErr *gossh.PassphraseMissingError
+	if errors.As(err, &missingErr) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("SSH key %s is protected by a passphrase, which was not provided", path)
+		}
+
+		signer, err = gossh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSH key %s: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+const defaultSSHKeyPassphraseEnv = "SSH_KEY_PASSPHRASE"
+
+// readSSHPassphrase reads the passphrase of the SSH keys from a file, or
+// else from an environment variable (SSH_KEY_PASSPHRASE by default).
+func readSSHPassphrase(file string, env string) (string, error) {
+	if strings.TrimSpace(file) != "" {
+		passphrase, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read SSH key passphrase file %s: %w", file, err)
+		}
+
+		return strings.TrimRight(string(passphrase), "\r\n"), nil
+	}
+
+	if strings.TrimSpace(env) == "" {
+		env = defaultSSHKeyPassphraseEnv
+	}
+
+	return os.Getenv(env), nil
+}
+
+func (c *sshCredentials) available() bool {
+	return len(c.keys) > 0 || c.agent != nil
+}
+
+func (c *sshCredentials) signers() ([]gossh.Signer, error) {
+	signers := append([]gossh.Signer{}, c.keys...)
+
+	if c.agent != nil {
+		agentSigners, err := c.agent.Signers()
+		if err != nil {
+			log.Debugf("Failed to list SSH agent keys: %v", err)
+		}
+
+		signers = append(signers, agentSigners...)
+	}
+
+	return signers, nil
+}
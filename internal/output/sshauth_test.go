@@ -0,0 +1,344 @@
+package output
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testSSHServer accepts the connections authenticated with one of its
+// authorized keys, and records every key offered to it.
+type testSSHServer struct {
+	addr string
+
+	mu      sync.Mutex
+	offered [][]byte
+}
+
+func newTestSSHServer(t *testing.T, authorized ...gossh.PublicKey) *testSSHServer {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostSigner, err := gossh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testSSHServer{addr: listener.Addr().String()}
+
+	config := &gossh.ServerConfig{
+		PublicKeyCallback: func(_ gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			// Keys are queried before being used to sign, only the first
+			// occurrence is kept.
+			if n := len(s.offered); n == 0 || !bytes.Equal(s.offered[n-1], key.Marshal()) {
+				s.offered = append(s.offered, key.Marshal())
+			}
+
+			for _, k := range authorized {
+				if bytes.Equal(k.Marshal(), key.Marshal()) {
+					return nil, nil
+				}
+			}
+
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// Authenticated connections are held until the client closes
+				// them, without serving anything.
+				sc, channels, requests, err := gossh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sc.Close()
+
+				go gossh.DiscardRequests(requests)
+				for channel := range channels {
+					channel.Reject(gossh.Prohibited, "no service")
+				}
+			}()
+		}
+	}()
+
+	return s
+}
+
+// offeredKeys returns the keys offered so far, in order.
+func (s *testSSHServer) offeredKeys() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]byte{}, s.offered...)
+}
+
+// connect authenticates to the server with the SSH auth of a filesystem
+// output using the given credentials.
+func (s *testSSHServer) connect(t *testing.T, credentials *sshCredentials) error {
+	t.Helper()
+
+	hostKeys, err := newHostKeyVerifier(HOST_KEY_POLICY_TOFU, filepath.Join(t.TempDir(), "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := filesystemOutputFormatter{ssh: credentials, hostKeys: hostKeys}
+
+	auth, err := o.createSSHAuth("ssh://git@" + s.addr + "/acme/repo.git")
+	if err != nil {
+		return err
+	}
+
+	config, err := auth.(*ssh.PublicKeysCallback).ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := gossh.Dial("tcp", s.addr, config)
+	if err != nil {
+		return err
+	}
+
+	return client.Close()
+}
+
+type testSSHKey struct {
+	signer gossh.Signer
+	path   string
+}
+
+// newTestSSHKey writes a new private key to a file, encrypted when a
+// passphrase is given.
+func newTestSSHKey(t *testing.T, passphrase string) testSSHKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = gossh.MarshalPrivateKey(key, "")
+	} else {
+		block, err = gossh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return testSSHKey{signer, path}
+}
+
+// withoutAgent keeps the SSH agent of the environment out of the tests.
+func withoutAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+}
+
+func TestSSHKeysAreOfferedInOrder(t *testing.T) {
+	withoutAgent(t)
+
+	unauthorized := newTestSSHKey(t, "")
+	authorized := newTestSSHKey(t, "")
+	server := newTestSSHServer(t, authorized.signer.PublicKey())
+
+	credentials, err := newSSHCredentials([]string{unauthorized.path, authorized.path}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.connect(t, credentials); err != nil {
+		t.Fatalf("authentication should fall back to the second key: %v", err)
+	}
+
+	offered := server.offeredKeys()
+	if len(offered) != 2 ||
+		!bytes.Equal(offered[0], unauthorized.signer.PublicKey().Marshal()) ||
+		!bytes.Equal(offered[1], authorized.signer.PublicKey().Marshal()) {
+		t.Errorf("keys should be offered in the given order, got %d key(s)", len(offered))
+	}
+}
+
+func TestSSHAuthenticationFailsWithoutAuthorizedKey(t *testing.T) {
+	withoutAgent(t)
+
+	key := newTestSSHKey(t, "")
+	server := newTestSSHServer(t, newTestSSHKey(t, "").signer.PublicKey())
+
+	credentials, err := newSSHCredentials([]string{key.path}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.connect(t, credentials)
+	if err == nil {
+		t.Fatal("authentication should fail")
+	}
+	if !isSSHAuthError(err) {
+		t.Errorf("error should be an SSH authentication error: %v", err)
+	}
+}
+
+func TestSSHEncryptedKeyWithPassphraseFile(t *testing.T) {
+	withoutAgent(t)
+	t.Setenv(defaultSSHKeyPassphraseEnv, "")
+
+	key := newTestSSHKey(t, "s3cret")
+	server := newTestSSHServer(t, key.signer.PublicKey())
+
+	file := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(file, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	passphrase, err := readSSHPassphrase(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, err := newSSHCredentials([]string{key.path}, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.connect(t, credentials); err != nil {
+		t.Fatalf("authentication should succeed: %v", err)
+	}
+}
+
+func TestSSHEncryptedKeyWithPassphraseEnv(t *testing.T) {
+	withoutAgent(t)
+
+	key := newTestSSHKey(t, "s3cret")
+	server := newTestSSHServer(t, key.signer.PublicKey())
+
+	for _, env := range []string{"", "VACUUM_TEST_PASSPHRASE"} {
+		name := env
+		if name == "" {
+			name = defaultSSHKeyPassphraseEnv
+		}
+		t.Setenv(name, "s3cret")
+
+		passphrase, err := readSSHPassphrase("", env)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		credentials, err := newSSHCredentials([]string{key.path}, passphrase)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if err := server.connect(t, credentials); err != nil {
+			t.Fatalf("%s: authentication should succeed: %v", name, err)
+		}
+	}
+}
+
+func TestSSHEncryptedKeyWithoutPassphrase(t *testing.T) {
+	withoutAgent(t)
+
+	key := newTestSSHKey(t, "s3cret")
+
+	_, err := newSSHCredentials([]string{key.path}, "")
+	if err == nil || !strings.Contains(err.Error(), "protected by a passphrase") {
+		t.Errorf("a missing passphrase should be reported, got %v", err)
+	}
+}
+
+func TestSSHEncryptedKeyWithWrongPassphrase(t *testing.T) {
+	withoutAgent(t)
+
+	key := newTestSSHKey(t, "s3cret")
+
+	if _, err := newSSHCredentials([]string{key.path}, "wrong"); err == nil {
+		t.Error("a wrong passphrase should be reported")
+	}
+}
+
+func TestSSHAgentSignersAreOfferedAfterKeys(t *testing.T) {
+	key := newTestSSHKey(t, "")
+
+	_, agentKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: agentKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	agentSigners, err := keyring.Signers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentPublicKey := agentSigners[0].PublicKey()
+
+	server := newTestSSHServer(t, agentPublicKey)
+
+	credentials := &sshCredentials{keys: []gossh.Signer{key.signer}, agent: keyring}
+	if err := server.connect(t, credentials); err != nil {
+		t.Fatalf("authentication should fall back to the agent key: %v", err)
+	}
+
+	offered := server.offeredKeys()
+	if len(offered) != 2 ||
+		!bytes.Equal(offered[0], key.signer.PublicKey().Marshal()) ||
+		!bytes.Equal(offered[1], agentPublicKey.Marshal()) {
+		t.Errorf("the agent key should be offered after the given keys, got %d key(s)", len(offered))
+	}
+}
+
+func TestSSHAuthRequiresKeyOrAgent(t *testing.T) {
+	o := filesystemOutputFormatter{ssh: &sshCredentials{}}
+
+	if _, err := o.createSSHAuth("ssh://git@example.com/acme/repo.git"); err == nil {
+		t.Error("SSH auth should not be created without any key nor agent")
+	}
+}
@@ -0,0 +1,175 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+// The state is saved at most this often while repositories are processed, so
+// that a run killed abruptly loses little of its progress.
+const stateSaveInterval = 10 * time.Second
+
+// State remembers the local copies cloned or updated successfully by previous
+// runs, so that a run resumes where the last one stopped. It is shared by the
+// filesystem outputs of a run, and its methods are no-ops on a nil state.
+type State struct {
+	path string
+
+	mu    sync.Mutex
+	saved time.Time
+	data  stateData
+}
+
+type stateData struct {
+	// Repositories are keyed by the absolute path of their local copy, so
+	// that a copy made to another folder is not taken for this one.
+	Repositories map[string]stateRepository `json:"repositories"`
+}
+
+type stateRepository struct {
+	ProcessedAt time.Time  `json:"processed_at"`
+	Commit      string     `json:"commit,omitempty"`
+	PushedAt    *time.Time `json:"pushed_at,omitempty"`
+}
+
+// LoadState reads the state file at path, which does not have to exist yet.
+// It returns a nil state when no path is given.
+func LoadState(path string) (*State, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	s := &State{
+		path:  path,
+		saved: time.Now(),
+		data:  stateData{Repositories: map[string]stateRepository{}},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", path, err)
+	}
+
+	if s.data.Repositories == nil {
+		s.data.Repositories = map[string]stateRepository{}
+	}
+
+	return s, nil
+}
+
+func stateKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
+
+// unchanged returns the state of the local copy at path, when it was cloned
+// or updated successfully and the provider tells the repository was not
+// pushed since. Repositories without a push time are never considered
+// unchanged.
+func (s *State) unchanged(r provider.Repository, path string) (stateRepository, bool) {
+	if s == nil || r.PushedAt.IsZero() {
+		return stateRepository{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.data.Repositories[stateKey(path)]
+	if !exists || entry.PushedAt == nil || r.PushedAt.After(*entry.PushedAt) {
+		return stateRepository{}, false
+	}
+
+	return entry, true
+}
+
+// record stores the outcome of the local copy at path. Failed repositories
+// are forgotten so that the next run retries them.
+func (s *State) record(r provider.Repository, path string, commit string, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		delete(s.data.Repositories, stateKey(path))
+	} else {
+		var pushedAt *time.Time
+		if t := r.PushedAt; !t.IsZero() {
+			pushedAt = &t
+		}
+
+		s.data.Repositories[stateKey(path)] = stateRepository{
+			ProcessedAt: time.Now().UTC(),
+			Commit:      commit,
+			PushedAt:    pushedAt,
+		}
+	}
+
+	if time.Since(s.saved) >= stateSaveInterval {
+		if err := s.write(); err != nil {
+			log.Warn("Error saving state: ", err.Error())
+		}
+	}
+}
+
+func (s *State) save() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write()
+}
+
+// write replaces the state file through a rename, so that it is never left
+// half-written.
+func (s *State) write() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+
+	s.saved = time.Now()
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/jdecool/github-vacuum/internal/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	errDiverged     = errors.New("local branch has diverged from the remote one")
+	errLocalChanges = errors.New("repository has local changes")
+)
+
+// sync updates a repository already on disk: every remote is fetched, then
+// the default branch is fast-forwarded to its origin counterpart. Bare
+// repositories are only fetched. It returns the transport used to fetch the
+// first remote.
+func (o filesystemOutputFormatter) sync(r provider.Repository, path string) (string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return "", err
+	}
+
+	method, err := o.fetchAll(r, repo)
+	if err != nil {
+		return method, err
+	}
+
+	wt, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return method, nil
+	}
+	if err != nil {
+		return method, err
+	}
+
+	if strings.TrimSpace(r.DefaultBranch) == "" {
+		log.Debugf("No default branch known for %s, only fetched", r.Fullname())
+		return method, nil
+	}
+
+	return method, fastForward(repo, wt, r.DefaultBranch)
+}
+
+func isShallow(repo *git.Repository) bool {
+	shallows, err := repo.Storer.Shallow()
+	return err == nil && len(shallows) > 0
+}
+
+// updateMirror fetches every ref of a mirror, deleting the ones that no
+// longer exist on the remote.
+func (o filesystemOutputFormatter) updateMirror(r provider.Repository, path string) (string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return "", err
+	}
+
+	return o.fetchAll(r, repo)
+}
+
+func (o filesystemOutputFormatter) fetchAll(r provider.Repository, repo *git.Repository) (string, error) {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return "", err
+	}
+
+	firstMethod := ""
+
+	// go-git refuses to fetch a given depth into a complete repository.
+	depth := 0
+	if isShallow(repo) {
+		depth = o.opts.Depth
+	}
+
+	for _, remote := range remotes {
+		url := remote.Config().URLs[0]
+
+		method := "HTTPS"
+		if endpoint, err := transport.NewEndpoint(url); err == nil && endpoint.Protocol == "ssh" {
+			method = "SSH"
+		}
+
+		if firstMethod == "" {
+			firstMethod = method
+		}
+
+		auth, err := o.createAuth(r, method, url)
+		if err != nil {
+			return firstMethod, err
+		}
+
+		log.Debugf("Fetching remote %s using %s", remote.Config().Name, method)
+
+		err = remote.FetchContext(o.opts.Context, &git.FetchOptions{
+			Auth:  auth,
+			Depth: depth,
+			Prune: remote.Config().Mirror,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return firstMethod, fmt.Errorf("failed to fetch remote %s: %w", remote.Config().Name, err)
+		}
+	}
+
+	return firstMethod, nil
+}
+
+func fastForward(repo *git.Repository, wt *git.Worktree, branch string) error {
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch), true)
+	if err != nil {
+		return fmt.Errorf("failed to find remote branch %s: %w", branch, err)
+	}
+
+	localName := plumbing.NewBranchReferenceName(branch)
+	localRef, err := repo.Reference(localName, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return repo.Storer.SetReference(plumbing.NewHashReference(localName, remoteRef.Hash()))
+	}
+	if err != nil {
+		return err
+	}
+
+	if localRef.Hash() == remoteRef.Hash() {
+		return nil
+	}
+
+	localCommit, err := repo.CommitObject(localRef.Hash())
+	if err != nil {
+		return err
+	}
+
+	remoteCommit, err := repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return err
+	}
+
+	isAncestor, err := localCommit.IsAncestor(remoteCommit)
+	if errors.Is(err, plumbing.ErrObjectNotFound) && isShallow(repo) {
+		// The history between both commits was not fetched, the local
+		// branch only follows the remote one.
+		isAncestor, err = true, nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isAncestor {
+		isAhead, err := remoteCommit.IsAncestor(localCommit)
+		if err != nil {
+			return err
+		}
+		if isAhead {
+			return fmt.Errorf("%w: %s has unpushed commits", errLocalChanges, branch)
+		}
+
+		return fmt.Errorf("%w: %s", errDiverged, branch)
+	}
+
+	head, err := repo.Head()
+	if err != nil || head.Name() != localName {
+		// The branch is not checked out, so only its reference moves.
+		return repo.Storer.SetReference(plumbing.NewHashReference(localName, remoteRef.Hash()))
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		return fmt.Errorf("%w: working tree is not clean", errLocalChanges)
+	}
+
+	return wt.Reset(&git.ResetOptions{
+		Commit: remoteRef.Hash(),
+		Mode:   git.HardReset,
+	})
+}
+
+// pushMarker is the file of the git directory recording the push time told by
+// the provider when the repository was last cloned or updated.
+const pushMarker = "github-vacuum-pushed-at"
+
+func markerPath(path string) string {
+	if info, err := os.Stat(filepath.Join(path, ".git")); err == nil && info.IsDir() {
+		return filepath.Join(path, ".git", pushMarker)
+	}
+
+	return filepath.Join(path, pushMarker)
+}
+
+// unchanged tells whether the repository was not pushed since the copy at
+// path was last cloned or updated. Repositories without a push time are
+// always considered changed.
+func (o filesystemOutputFormatter) unchanged(r provider.Repository, path string) bool {
+	if r.PushedAt.IsZero() {
+		return false
+	}
+
+	data, err := os.ReadFile(markerPath(path))
+	if err != nil {
+		return false
+	}
+
+	marked, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Debugf("Ignoring invalid push marker of %s: %v", r.Fullname(), err)
+		return false
+	}
+
+	return !r.PushedAt.After(marked)
+}
+
+func (o filesystemOutputFormatter) markPushed(r provider.Repository, path string) {
+	if r.PushedAt.IsZero() {
+		return
+	}
+
+	data := []byte(r.PushedAt.UTC().Format(time.RFC3339Nano) + "\n")
+	if err := os.WriteFile(markerPath(path), data, 0644); err != nil {
+		log.Warnf("Failed to record push time of %s: %v", r.Fullname(), err)
+	}
+}
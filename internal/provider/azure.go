@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	azureDefaultEndpoint = "https://dev.azure.com"
+	azureAPIVersion      = "7.0"
+)
+
+type azureProvider struct {
+	client restClient
+}
+
+type azureProject struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+}
+
+type azureRepository struct {
+	Name          string       `json:"name"`
+	RemoteURL     string       `json:"remoteUrl"`
+	SSHURL        string       `json:"sshUrl"`
+	DefaultBranch string       `json:"defaultBranch"`
+	IsDisabled    bool         `json:"isDisabled"`
+	IsFork        bool         `json:"isFork"`
+	Project       azureProject `json:"project"`
+}
+
+type azureList[T any] struct {
+	Value []T `json:"value"`
+}
+
+func newAzureProviderClient(options ProviderOptions) (*azureProvider, error) {
+	endpoint := strings.TrimSpace(options.EndpointUrl)
+	if endpoint == "" {
+		endpoint = azureDefaultEndpoint
+	}
+
+	return &azureProvider{
+		newRestClient(options.Context, endpoint, func(req *http.Request) {
+			// Personal access tokens are sent as the password of a basic auth
+			// header, the username being ignored.
+			if strings.TrimSpace(options.AccessToken) != "" {
+				req.SetBasicAuth(options.Username, options.AccessToken)
+			}
+		}),
+	}, nil
+}
+
+func (p azureProvider) GetName() string {
+	return PROVIDER_AZURE
+}
+
+func (p azureProvider) GetOrganizations(filter []string) ([]string, error) {
+	if len(filter) == 0 {
+		return nil, errors.New("Azure DevOps organizations should be specified.")
+	}
+
+	var errorList error
+
+	r := []string{}
+	for _, org := range filter {
+		query := azureQuery()
+		query.Set("$top", "1")
+
+		var projects azureList[azureProject]
+		resp, err := p.client.get(url.PathEscape(org)+"/_apis/projects", query, &projects)
+		if err != nil {
+			errorList = appendError(errorList, err)
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				break
+			}
+
+			continue
+		}
+
+		r = append(r, org)
+	}
+
+	return r, errorList
+}
+
+func (p azureProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	var r []Repository
+	var errorList error
+
+	projects, err := p.getProjects(org)
+	if err != nil {
+		errorList = appendError(errorList, err)
+	}
+
+	for _, project := range projects {
+		log.Debugf("Processing project %s for org %s", project.Name, org)
+
+		var repos azureList[azureRepository]
+		path := url.PathEscape(org) + "/" + url.PathEscape(project.Name) + "/_apis/git/repositories"
+		if _, err := p.client.get(path, azureQuery(), &repos); err != nil {
+			errorList = appendError(errorList, err)
+			continue
+		}
+
+		for _, repo := range repos.Value {
+			if repo.IsDisabled {
+				log.Debugf("Ignoring disabled repository %s/%s", project.Name, repo.Name)
+				continue
+			}
+
+			r = append(r, Repository{
+				Provider:      p,
+				Owner:         org,
+				Path:          repo.Project.Name + "/" + repo.Name,
+				Name:          repo.Name,
+				CloneURL:      repo.RemoteURL,
+				SSHUrl:        repo.SSHURL,
+				DefaultBranch: strings.TrimPrefix(repo.DefaultBranch, "refs/heads/"),
+				Fork:          repo.IsFork,
+				Visibility:    strings.ToLower(repo.Project.Visibility),
+			})
+		}
+	}
+
+	return r, errorList
+}
+
+func (p azureProvider) GetUserRepositories(username string) ([]Repository, error) {
+	return nil, errors.New("Azure DevOps does not support user repositories.")
+}
+
+func (p azureProvider) getProjects(org string) ([]azureProject, error) {
+	var r []azureProject
+
+	query := azureQuery()
+	query.Set("$top", "100")
+	for {
+		var projects azureList[azureProject]
+		resp, err := p.client.get(url.PathEscape(org)+"/_apis/projects", query, &projects)
+		if err != nil {
+			return r, err
+		}
+
+		r = append(r, projects.Value...)
+
+		token := resp.Header.Get("X-MS-ContinuationToken")
+		if token == "" {
+			break
+		}
+
+		query.Set("continuationToken", token)
+	}
+
+	return r, nil
+}
+
+func azureQuery() url.Values {
+	return url.Values{
+		"api-version": {azureAPIVersion},
+	}
+}
@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const bitbucketDefaultEndpoint = "https://api.bitbucket.org/2.0"
+
+type bitbucketProvider struct {
+	client restClient
+}
+
+type bitbucketWorkspace struct {
+	Slug string `json:"slug"`
+}
+
+type bitbucketLink struct {
+	Name string `json:"name"`
+	Href string `json:"href"`
+}
+
+type bitbucketRepository struct {
+	Name       string             `json:"name"`
+	Slug       string             `json:"slug"`
+	FullName   string             `json:"full_name"`
+	Workspace  bitbucketWorkspace `json:"workspace"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Links struct {
+		Clone []bitbucketLink `json:"clone"`
+	} `json:"links"`
+	IsPrivate bool `json:"is_private"`
+	// Parent is only set on forks.
+	Parent    *struct{} `json:"parent"`
+	CreatedOn time.Time `json:"created_on"`
+	// UpdatedOn is bumped by every push, Bitbucket not tracking pushes
+	// apart.
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+type bitbucketPage[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+func newBitbucketProviderClient(options ProviderOptions) (*bitbucketProvider, error) {
+	endpoint := strings.TrimSpace(options.EndpointUrl)
+	if endpoint == "" {
+		endpoint = bitbucketDefaultEndpoint
+	}
+
+	return &bitbucketProvider{
+		newRestClient(options.Context, endpoint, bitbucketAuthorization(options)),
+	}, nil
+}
+
+// bitbucketAuthorization uses HTTP basic auth when a username is given (app
+// passwords, Data Center personal tokens), and a bearer token otherwise
+// (workspace, project and repository access tokens).
+func bitbucketAuthorization(options ProviderOptions) func(req *http.Request) {
+	return func(req *http.Request) {
+		if strings.TrimSpace(options.AccessToken) == "" {
+			return
+		}
+
+		if strings.TrimSpace(options.Username) != "" {
+			req.SetBasicAuth(options.Username, options.AccessToken)
+			return
+		}
+
+		req.Header.Set("Authorization", "Bearer "+options.AccessToken)
+	}
+}
+
+func (p bitbucketProvider) GetName() string {
+	return PROVIDER_BITBUCKET
+}
+
+func (p bitbucketProvider) GetOrganizations(filter []string) ([]string, error) {
+	if len(filter) == 0 {
+		return p.getAllOrganizations()
+	}
+
+	var errorList error
+
+	r := []string{}
+	for _, org := range filter {
+		var w bitbucketWorkspace
+		resp, err := p.client.get("workspaces/"+url.PathEscape(org), nil, &w)
+		if err != nil {
+			errorList = appendError(errorList, err)
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				break
+			}
+
+			continue
+		}
+
+		r = append(r, w.Slug)
+	}
+
+	return r, errorList
+}
+
+func (p bitbucketProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	log.Debugf("Processing repositories for workspace %s", org)
+
+	return p.listRepositories(org)
+}
+
+// GetUserRepositories lists the repositories of the user's personal
+// workspace, whose slug is the username.
+func (p bitbucketProvider) GetUserRepositories(username string) ([]Repository, error) {
+	log.Debugf("Processing repositories for user %s", username)
+
+	return p.listRepositories(username)
+}
+
+func (p bitbucketProvider) listRepositories(workspace string) ([]Repository, error) {
+	var r []Repository
+
+	path := "repositories/" + url.PathEscape(workspace)
+	query := url.Values{"pagelen": {"100"}}
+	for path != "" {
+		log.Debugf("Processing page %s", path)
+
+		var page bitbucketPage[bitbucketRepository]
+		if _, err := p.client.get(path, query, &page); err != nil {
+			return r, err
+		}
+
+		for _, repo := range page.Values {
+			cloneURL, sshURL := bitbucketCloneLinks(repo.Links.Clone)
+
+			defaultBranch := ""
+			if repo.MainBranch != nil {
+				defaultBranch = repo.MainBranch.Name
+			}
+
+			visibility := VISIBILITY_PUBLIC
+			if repo.IsPrivate {
+				visibility = VISIBILITY_PRIVATE
+			}
+
+			r = append(r, Repository{
+				Provider:      p,
+				Owner:         repo.Workspace.Slug,
+				Path:          repo.FullName,
+				Name:          repo.Slug,
+				CloneURL:      cloneURL,
+				SSHUrl:        sshURL,
+				DefaultBranch: defaultBranch,
+				Fork:          repo.Parent != nil,
+				Visibility:    visibility,
+				CreatedAt:     repo.CreatedOn,
+				UpdatedAt:     repo.UpdatedOn,
+				PushedAt:      repo.UpdatedOn,
+			})
+		}
+
+		path = page.Next
+		query = nil
+	}
+
+	return r, nil
+}
+
+func (p bitbucketProvider) getAllOrganizations() ([]string, error) {
+	r := []string{}
+
+	path := "user/permissions/workspaces"
+	query := url.Values{"pagelen": {"100"}}
+	for path != "" {
+		var page bitbucketPage[struct {
+			Workspace bitbucketWorkspace `json:"workspace"`
+		}]
+		if _, err := p.client.get(path, query, &page); err != nil {
+			return r, err
+		}
+
+		for _, w := range page.Values {
+			r = append(r, w.Workspace.Slug)
+		}
+
+		path = page.Next
+		query = nil
+	}
+
+	return r, nil
+}
+
+// bitbucketCloneLinks extracts the HTTPS and SSH clone URLs. Bitbucket Cloud
+// names the HTTPS link "https" while Bitbucket Data Center names it "http",
+// whatever the scheme actually is.
+func bitbucketCloneLinks(links []bitbucketLink) (cloneURL string, sshURL string) {
+	for _, link := range links {
+		switch link.Name {
+		case "https", "http":
+			cloneURL = link.Href
+		case "ssh":
+			sshURL = link.Href
+		}
+	}
+
+	return cloneURL, sshURL
+}
@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type bitbucketServerProvider struct {
+	client restClient
+}
+
+type bitbucketServerProject struct {
+	Key string `json:"key"`
+}
+
+type bitbucketServerRepository struct {
+	Name    string                 `json:"name"`
+	Slug    string                 `json:"slug"`
+	Project bitbucketServerProject `json:"project"`
+	Links   struct {
+		Clone []bitbucketLink `json:"clone"`
+	} `json:"links"`
+	Public   bool `json:"public"`
+	Archived bool `json:"archived"`
+	// Origin is only set on forks.
+	Origin *struct{} `json:"origin"`
+}
+
+type bitbucketServerPage[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+func newBitbucketServerProviderClient(options ProviderOptions) (*bitbucketServerProvider, error) {
+	endpoint := strings.TrimSpace(options.EndpointUrl)
+	if endpoint == "" {
+		return nil, errors.New("Bitbucket Data Center provider requires an endpoint.")
+	}
+	endpoint = strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), "/rest/api/1.0")
+
+	return &bitbucketServerProvider{
+		newRestClient(options.Context, endpoint+"/rest/api/1.0", bitbucketAuthorization(options)),
+	}, nil
+}
+
+func (p bitbucketServerProvider) GetName() string {
+	return PROVIDER_BITBUCKET_SERVER
+}
+
+func (p bitbucketServerProvider) GetOrganizations(filter []string) ([]string, error) {
+	if len(filter) == 0 {
+		return p.getAllOrganizations()
+	}
+
+	var errorList error
+
+	r := []string{}
+	for _, org := range filter {
+		var project bitbucketServerProject
+		resp, err := p.client.get("projects/"+url.PathEscape(org), nil, &project)
+		if err != nil {
+			errorList = appendError(errorList, err)
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				break
+			}
+
+			continue
+		}
+
+		r = append(r, project.Key)
+	}
+
+	return r, errorList
+}
+
+func (p bitbucketServerProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	log.Debugf("Processing repositories for project %s", org)
+
+	return p.listRepositories("projects/" + url.PathEscape(org) + "/repos")
+}
+
+// GetUserRepositories lists the repositories of the user's personal project.
+func (p bitbucketServerProvider) GetUserRepositories(username string) ([]Repository, error) {
+	log.Debugf("Processing repositories for user %s", username)
+
+	return p.listRepositories("users/" + url.PathEscape(username) + "/repos")
+}
+
+func (p bitbucketServerProvider) listRepositories(path string) ([]Repository, error) {
+	var r []Repository
+	var errorList error
+
+	start := 0
+	for {
+		log.Debugf("Processing page starting at %d of %s", start, path)
+
+		var page bitbucketServerPage[bitbucketServerRepository]
+		if _, err := p.client.get(path, bitbucketServerPageQuery(start), &page); err != nil {
+			return r, appendError(errorList, err)
+		}
+
+		for _, repo := range page.Values {
+			cloneURL, sshURL := bitbucketCloneLinks(repo.Links.Clone)
+
+			defaultBranch, err := p.getDefaultBranch(repo)
+			if err != nil {
+				errorList = appendError(errorList, err)
+			}
+
+			// Clone URLs use the lower-cased project key, e.g. "~jdoe" for a
+			// personal project.
+			owner := strings.ToLower(repo.Project.Key)
+
+			visibility := VISIBILITY_PRIVATE
+			if repo.Public {
+				visibility = VISIBILITY_PUBLIC
+			}
+
+			r = append(r, Repository{
+				Provider:      p,
+				Owner:         owner,
+				Path:          owner + "/" + repo.Slug,
+				Name:          repo.Slug,
+				CloneURL:      cloneURL,
+				SSHUrl:        sshURL,
+				DefaultBranch: defaultBranch,
+				Fork:          repo.Origin != nil,
+				Archived:      repo.Archived,
+				Visibility:    visibility,
+			})
+		}
+
+		if page.IsLastPage {
+			break
+		}
+
+		start = page.NextPageStart
+	}
+
+	return r, errorList
+}
+
+func (p bitbucketServerProvider) getDefaultBranch(repo bitbucketServerRepository) (string, error) {
+	var branch struct {
+		DisplayID string `json:"displayId"`
+	}
+
+	path := "projects/" + url.PathEscape(repo.Project.Key) + "/repos/" + url.PathEscape(repo.Slug) + "/branches/default"
+	resp, err := p.client.get(path, nil, &branch)
+	if err != nil {
+		// Empty repositories have no default branch yet.
+		if resp != nil && resp.StatusCode == 404 {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return branch.DisplayID, nil
+}
+
+func (p bitbucketServerProvider) getAllOrganizations() ([]string, error) {
+	r := []string{}
+
+	start := 0
+	for {
+		var page bitbucketServerPage[bitbucketServerProject]
+		if _, err := p.client.get("projects", bitbucketServerPageQuery(start), &page); err != nil {
+			return r, err
+		}
+
+		for _, project := range page.Values {
+			r = append(r, project.Key)
+		}
+
+		if page.IsLastPage {
+			break
+		}
+
+		start = page.NextPageStart
+	}
+
+	return r, nil
+}
+
+func bitbucketServerPageQuery(start int) url.Values {
+	return url.Values{
+		"start": {strconv.Itoa(start)},
+		"limit": {"100"},
+	}
+}
@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type fileProvider struct {
+	repositories inventory
+}
+
+type inventory []inventoryEntry
+
+// inventoryEntry is a repository as described in an inventory file. Only
+// the owner and either the name or the path are required.
+type inventoryEntry struct {
+	Owner         string `json:"owner" yaml:"owner"`
+	Path          string `json:"path" yaml:"path"`
+	Name          string `json:"name" yaml:"name"`
+	CloneURL      string `json:"clone_url" yaml:"clone_url"`
+	SSHUrl        string `json:"ssh_url" yaml:"ssh_url"`
+	DefaultBranch string `json:"default_branch" yaml:"default_branch"`
+	Fork          bool   `json:"fork" yaml:"fork"`
+	Archived      bool   `json:"archived" yaml:"archived"`
+	Visibility    string `json:"visibility" yaml:"visibility"`
+	// PushedAt lets the inventories written by the outputs be replayed
+	// along with the last push time of their repositories.
+	PushedAt time.Time `json:"pushed_at" yaml:"pushed_at"`
+}
+
+func newFileProvider(options ProviderOptions) (*fileProvider, error) {
+	path := strings.TrimSpace(options.EndpointUrl)
+	if path == "" {
+		return nil, errors.New("File provider requires the inventory file as endpoint.")
+	}
+
+	entries, err := readInventory(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range entries {
+		if e.Name == "" && e.Path != "" {
+			e.Name = e.Path[strings.LastIndex(e.Path, "/")+1:]
+		}
+
+		if e.Owner == "" || e.Name == "" {
+			return nil, fmt.Errorf("inventory %s: entry %d should have an owner and a name", path, i+1)
+		}
+
+		if e.Path == "" {
+			e.Path = e.Owner + "/" + e.Name
+		}
+
+		entries[i] = e
+	}
+
+	return &fileProvider{inventory(entries)}, nil
+}
+
+func readInventory(path string) ([]inventoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []inventoryEntry
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&entries)
+	case ".ndjson", ".jsonl":
+		entries, err = readNDJSONInventory(f)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(f).Decode(&entries)
+	case ".csv":
+		entries, err = readCSVInventory(f)
+	default:
+		return nil, fmt.Errorf("inventory %s: unsupported format, expected .json, .ndjson, .yaml or .csv", path)
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("inventory %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// readNDJSONInventory reads a file holding one JSON object per line.
+func readNDJSONInventory(r io.Reader) ([]inventoryEntry, error) {
+	var entries []inventoryEntry
+
+	decoder := json.NewDecoder(r)
+	for {
+		var e inventoryEntry
+		if err := decoder.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// readCSVInventory reads a CSV file whose first line names the columns, using
+// the same names as the JSON and YAML keys. Unknown columns are ignored.
+func readCSVInventory(r io.Reader) ([]inventoryEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []inventoryEntry
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	value := func(record []string, column string) string {
+		i, exists := columns[column]
+		if !exists || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	flag := func(record []string, column string) (bool, error) {
+		v := value(record, column)
+		if v == "" {
+			return false, nil
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("line %d: invalid %s value %q", len(entries)+2, column, v)
+		}
+
+		return b, nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fork, err := flag(record, "fork")
+		if err != nil {
+			return nil, err
+		}
+
+		archived, err := flag(record, "archived")
+		if err != nil {
+			return nil, err
+		}
+
+		var pushedAt time.Time
+		if v := value(record, "pushed_at"); v != "" {
+			pushedAt, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid pushed_at value %q", len(entries)+2, v)
+			}
+		}
+
+		entries = append(entries, inventoryEntry{
+			Owner:         value(record, "owner"),
+			Path:          value(record, "path"),
+			Name:          value(record, "name"),
+			CloneURL:      value(record, "clone_url"),
+			SSHUrl:        value(record, "ssh_url"),
+			DefaultBranch: value(record, "default_branch"),
+			Fork:          fork,
+			Archived:      archived,
+			Visibility:    strings.ToLower(value(record, "visibility")),
+			PushedAt:      pushedAt,
+		})
+	}
+
+	return entries, nil
+}
+
+func (p fileProvider) GetName() string {
+	return PROVIDER_FILE
+}
+
+func (p fileProvider) GetOrganizations(filter []string) ([]string, error) {
+	return p.repositories.owners(filter)
+}
+
+func (p fileProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	return p.repositories.ownerRepositories(p, org), nil
+}
+
+func (p fileProvider) GetUserRepositories(username string) ([]Repository, error) {
+	r := p.repositories.ownerRepositories(p, username)
+	if len(r) == 0 {
+		return nil, fmt.Errorf("user %s not found in inventory", username)
+	}
+
+	return r, nil
+}
+
+// owners returns the distinct owners of the inventory, restricted to filter
+// when it is not empty.
+func (inv inventory) owners(filter []string) ([]string, error) {
+	var errorList error
+
+	owners := []string{}
+	seen := map[string]bool{}
+	for _, e := range inv {
+		if !seen[e.Owner] {
+			seen[e.Owner] = true
+			owners = append(owners, e.Owner)
+		}
+	}
+
+	if len(filter) == 0 {
+		return owners, nil
+	}
+
+	r := []string{}
+	for _, org := range filter {
+		if !seen[org] {
+			errorList = appendError(errorList, fmt.Errorf("organization %s not found in inventory", org))
+			continue
+		}
+
+		r = append(r, org)
+	}
+
+	return r, errorList
+}
+
+func (inv inventory) ownerRepositories(p Provider, owner string) []Repository {
+	var r []Repository
+
+	for _, e := range inv {
+		if e.Owner != owner {
+			continue
+		}
+
+		r = append(r, Repository{
+			Provider:      p,
+			Owner:         e.Owner,
+			Path:          e.Path,
+			Name:          e.Name,
+			CloneURL:      e.CloneURL,
+			SSHUrl:        e.SSHUrl,
+			DefaultBranch: e.DefaultBranch,
+			Fork:          e.Fork,
+			Archived:      e.Archived,
+			Visibility:    e.Visibility,
+			PushedAt:      e.PushedAt,
+		})
+	}
+
+	return r
+}
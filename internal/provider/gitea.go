@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const giteaDefaultEndpoint = "https://gitea.com"
+
+type giteaProvider struct {
+	client restClient
+}
+
+type giteaUser struct {
+	Login    string `json:"login"`
+	Name     string `json:"name"`
+	UserName string `json:"username"`
+}
+
+type giteaRepository struct {
+	Owner         giteaUser `json:"owner"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	CloneURL      string    `json:"clone_url"`
+	SSHURL        string    `json:"ssh_url"`
+	DefaultBranch string    `json:"default_branch"`
+	Fork          bool      `json:"fork"`
+	Archived      bool      `json:"archived"`
+	Private       bool      `json:"private"`
+	Internal      bool      `json:"internal"`
+	CreatedAt     time.Time `json:"created_at"`
+	// UpdatedAt is bumped by every push, Gitea not tracking pushes apart.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newGiteaProviderClient(options ProviderOptions) (*giteaProvider, error) {
+	return &giteaProvider{
+		createGiteaClient(options),
+	}, nil
+}
+
+func createGiteaClient(options ProviderOptions) restClient {
+	endpoint := strings.TrimSpace(options.EndpointUrl)
+	if endpoint == "" {
+		endpoint = giteaDefaultEndpoint
+	}
+	endpoint = strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), "/api/v1")
+
+	return newRestClient(options.Context, endpoint+"/api/v1", func(req *http.Request) {
+		if strings.TrimSpace(options.AccessToken) != "" {
+			req.Header.Set("Authorization", "token "+options.AccessToken)
+		}
+	})
+}
+
+func (p giteaProvider) GetName() string {
+	return PROVIDER_GITEA
+}
+
+func (p giteaProvider) GetOrganizations(filter []string) ([]string, error) {
+	if len(filter) == 0 {
+		return p.getAllOrganizations()
+	}
+
+	var errorList error
+
+	r := []string{}
+	for _, org := range filter {
+		var o giteaUser
+		resp, err := p.client.get("orgs/"+url.PathEscape(org), nil, &o)
+		if err != nil {
+			errorList = appendError(errorList, err)
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				break
+			}
+
+			continue
+		}
+
+		r = append(r, o.login())
+	}
+
+	return r, errorList
+}
+
+func (p giteaProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	log.Debugf("Processing repositories for org %s", org)
+
+	return p.listRepositories("orgs/" + url.PathEscape(org) + "/repos")
+}
+
+func (p giteaProvider) GetUserRepositories(username string) ([]Repository, error) {
+	path := "users/" + url.PathEscape(username) + "/repos"
+
+	var user giteaUser
+	if _, err := p.client.get("user", nil, &user); err == nil && user.login() == username {
+		path = "user/repos"
+		log.Debugf("Authenticated user requesting own repos - including private repositories")
+	}
+
+	log.Debugf("Processing repositories for user %s", username)
+
+	return p.listRepositories(path)
+}
+
+func (p giteaProvider) listRepositories(path string) ([]Repository, error) {
+	var r []Repository
+
+	query := giteaPageQuery()
+	for page := 1; path != ""; page++ {
+		log.Debugf("Processing page %d of %s", page, path)
+
+		var repos []giteaRepository
+		resp, err := p.client.get(path, query, &repos)
+		if err != nil {
+			return r, err
+		}
+
+		for _, repo := range repos {
+			r = append(r, Repository{
+				Provider:      p,
+				Owner:         repo.Owner.login(),
+				Path:          repo.FullName,
+				Name:          repo.Name,
+				CloneURL:      repo.CloneURL,
+				SSHUrl:        repo.SSHURL,
+				DefaultBranch: repo.DefaultBranch,
+				Fork:          repo.Fork,
+				Archived:      repo.Archived,
+				Visibility:    repo.visibility(),
+				CreatedAt:     repo.CreatedAt,
+				UpdatedAt:     repo.UpdatedAt,
+				PushedAt:      repo.UpdatedAt,
+			})
+		}
+
+		path = nextLink(resp)
+		query = nil
+	}
+
+	return r, nil
+}
+
+func (p giteaProvider) getAllOrganizations() ([]string, error) {
+	r := []string{}
+
+	path := "orgs"
+	query := giteaPageQuery()
+	for path != "" {
+		var orgs []giteaUser
+		resp, err := p.client.get(path, query, &orgs)
+		if err != nil {
+			return r, err
+		}
+
+		for _, o := range orgs {
+			r = append(r, o.login())
+		}
+
+		path = nextLink(resp)
+		query = nil
+	}
+
+	return r, nil
+}
+
+func giteaPageQuery() url.Values {
+	return url.Values{
+		"page":  {"1"},
+		"limit": {"50"},
+	}
+}
+
+// login returns the account name: users expose it as "login", organizations
+// as "name", and older Gitea releases only as "username".
+func (u giteaUser) login() string {
+	if u.Login != "" {
+		return u.Login
+	}
+
+	if u.Name != "" {
+		return u.Name
+	}
+
+	return u.UserName
+}
+
+func (r giteaRepository) visibility() string {
+	switch {
+	case r.Private:
+		return VISIBILITY_PRIVATE
+	case r.Internal:
+		return VISIBILITY_INTERNAL
+	default:
+		return VISIBILITY_PUBLIC
+	}
+}
@@ -2,10 +2,12 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/google/go-github/github"
+	"github.com/google/go-querystring/query"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
@@ -15,6 +17,14 @@ type githubProvider struct {
 	client *github.Client
 }
 
+// githubRepository adds the attributes unknown to the API client to its
+// repositories.
+type githubRepository struct {
+	github.Repository
+	IsTemplate *bool   `json:"is_template,omitempty"`
+	Visibility *string `json:"visibility,omitempty"`
+}
+
 func newGithubProviderClient(options ProviderOptions) (*githubProvider, error) {
 	c, err := createGithubClient(options)
 	if err != nil {
@@ -44,8 +54,9 @@ func createGithubClient(options ProviderOptions) (*github.Client, error) {
 }
 
 func createHttpClient(options ProviderOptions) *http.Client {
+	httpClient := newRetryClient()
 	if strings.TrimSpace(options.AccessToken) == "" {
-		return nil
+		return httpClient
 	}
 
 	tokenSource := oauth2.StaticTokenSource(
@@ -54,7 +65,10 @@ func createHttpClient(options ProviderOptions) *http.Client {
 		},
 	)
 
-	return oauth2.NewClient(options.Context, tokenSource)
+	// The token is added on top of the retrying client.
+	ctx := context.WithValue(options.Context, oauth2.HTTPClient, httpClient)
+
+	return oauth2.NewClient(ctx, tokenSource)
 }
 
 func (p githubProvider) GetName() string {
@@ -73,7 +87,7 @@ func (p githubProvider) GetOrganizations(filter []string) ([]string, error) {
 		o, resp, err := p.client.Organizations.Get(p.ctx, org)
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
 				break
 			}
 
@@ -101,26 +115,16 @@ func (p githubProvider) GetOrganizationRepositories(org string) ([]Repository, e
 	for {
 		log.Debugf("Processing page %d for org %s", opt.Page, org)
 
-		repos, resp, err := p.client.Repositories.ListByOrg(p.ctx, org, opt)
+		repos, resp, err := p.listRepositories(fmt.Sprintf("orgs/%v/repos", org), opt)
 		if err != nil {
+			// Transient failures were already retried by the HTTP client, the
+			// same page would most likely fail again.
 			errorList = appendError(errorList, err)
-			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, repo := range repos {
-			r = append(r, Repository{
-				Provider:      p,
-				Owner:         *repo.Owner.Login,
-				Path:          *repo.FullName,
-				Name:          *repo.Name,
-				CloneURL:      *repo.CloneURL,
-				SSHUrl:        *repo.SSHURL,
-				DefaultBranch: *repo.DefaultBranch,
-			})
+			r = append(r, p.newRepository(repo))
 		}
 
 		if resp.NextPage == 0 {
@@ -159,26 +163,14 @@ func (p githubProvider) GetUserRepositories(username string) ([]Repository, erro
 	for {
 		log.Debugf("Processing page %d for user %s", opt.Page, username)
 
-		repos, resp, err := p.client.Repositories.List(p.ctx, username, opt)
+		repos, resp, err := p.listRepositories(fmt.Sprintf("users/%v/repos", username), opt)
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, repo := range repos {
-			r = append(r, Repository{
-				Provider:      p,
-				Owner:         *repo.Owner.Login,
-				Path:          *repo.FullName,
-				Name:          *repo.Name,
-				CloneURL:      *repo.CloneURL,
-				SSHUrl:        *repo.SSHURL,
-				DefaultBranch: *repo.DefaultBranch,
-			})
+			r = append(r, p.newRepository(repo))
 		}
 
 		if resp.NextPage == 0 {
@@ -191,6 +183,70 @@ func (p githubProvider) GetUserRepositories(username string) ([]Repository, erro
 	return r, errorList
 }
 
+// listRepositories lists a page of repositories like the API client does,
+// decoding the attributes it does not know about as well.
+func (p githubProvider) listRepositories(path string, opt interface{}) ([]*githubRepository, *github.Response, error) {
+	v, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := p.client.NewRequest("GET", path+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Topics and template repositories were first released as previews of
+	// the API, which GitHub Enterprise Server may still require.
+	req.Header.Set("Accept", "application/vnd.github.mercy-preview+json, application/vnd.github.baptiste-preview+json")
+
+	var repos []*githubRepository
+	resp, err := p.client.Do(p.ctx, req, &repos)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return repos, resp, nil
+}
+
+func (p githubProvider) newRepository(repo *githubRepository) Repository {
+	// Older GitHub Enterprise Server versions do not report the visibility,
+	// internal repositories then being private ones.
+	visibility := ""
+	if repo.Visibility != nil {
+		visibility = strings.ToLower(*repo.Visibility)
+	}
+	if visibility == "" {
+		visibility = VISIBILITY_PUBLIC
+		if repo.GetPrivate() {
+			visibility = VISIBILITY_PRIVATE
+		}
+	}
+
+	return Repository{
+		Provider:      p,
+		Owner:         *repo.Owner.Login,
+		Path:          *repo.FullName,
+		Name:          *repo.Name,
+		CloneURL:      *repo.CloneURL,
+		SSHUrl:        *repo.SSHURL,
+		DefaultBranch: *repo.DefaultBranch,
+		Description:   repo.GetDescription(),
+		Topics:        repo.Topics,
+		Language:      repo.GetLanguage(),
+		// Sizes are reported in kilobytes.
+		Size:       int64(repo.GetSize()) * 1024,
+		Stars:      repo.GetStargazersCount(),
+		Fork:       repo.GetFork(),
+		Archived:   repo.GetArchived(),
+		Template:   repo.IsTemplate != nil && *repo.IsTemplate,
+		Visibility: visibility,
+		CreatedAt:  repo.GetCreatedAt().Time,
+		UpdatedAt:  repo.GetUpdatedAt().Time,
+		PushedAt:   repo.GetPushedAt().Time,
+	}
+}
+
 func (p githubProvider) getAllOrganizations() ([]string, error) {
 	var errorList error
 	r := []string{}
@@ -203,11 +259,7 @@ func (p githubProvider) getAllOrganizations() ([]string, error) {
 		orgs, resp, err := p.client.Organizations.ListAll(p.ctx, opt)
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, o := range orgs {
@@ -222,5 +274,5 @@ func (p githubProvider) getAllOrganizations() ([]string, error) {
 
 	}
 
-	return r, nil
+	return r, errorList
 }
@@ -1,15 +1,20 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/xanzy/go-gitlab"
 )
 
 type gitlabProvider struct {
-	client *gitlab.Client
+	ctx       context.Context
+	client    *gitlab.Client
+	languages bool
 }
 
 func newGitlabProviderClient(options ProviderOptions) (*gitlabProvider, error) {
@@ -19,16 +24,25 @@ func newGitlabProviderClient(options ProviderOptions) (*gitlabProvider, error) {
 	}
 
 	return &gitlabProvider{
+		options.Context,
 		c,
+		options.Languages,
 	}, nil
 }
 
 func createGitlabClient(options ProviderOptions) (*gitlab.Client, error) {
-	if strings.TrimSpace(options.EndpointUrl) == "" {
-		return gitlab.NewClient(options.AccessToken)
+	// Requests are retried by the shared HTTP client rather than by the
+	// GitLab client, so that every provider behaves the same.
+	clientOptions := []gitlab.ClientOptionFunc{
+		gitlab.WithHTTPClient(newRetryClient()),
+		gitlab.WithoutRetries(),
 	}
 
-	return gitlab.NewClient(options.AccessToken, gitlab.WithBaseURL(options.EndpointUrl))
+	if strings.TrimSpace(options.EndpointUrl) != "" {
+		clientOptions = append(clientOptions, gitlab.WithBaseURL(options.EndpointUrl))
+	}
+
+	return gitlab.NewClient(options.AccessToken, clientOptions...)
 }
 
 func (p gitlabProvider) GetName() string {
@@ -44,10 +58,10 @@ func (p gitlabProvider) GetOrganizations(filter []string) ([]string, error) {
 
 	r := []string{}
 	for _, org := range filter {
-		g, resp, err := p.client.Groups.GetGroup(org, nil)
+		g, resp, err := p.client.Groups.GetGroup(org, nil, gitlab.WithContext(p.ctx))
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
 				break
 			}
 
@@ -76,26 +90,16 @@ func (p gitlabProvider) GetOrganizationRepositories(org string) ([]Repository, e
 	for {
 		log.Debugf("Processing page %d", opt.Page)
 
-		repos, resp, err := p.client.Groups.ListGroupProjects(org, opt)
+		repos, resp, err := p.client.Groups.ListGroupProjects(org, opt, gitlab.WithContext(p.ctx), withStatistics())
 		if err != nil {
+			// Transient failures were already retried by the HTTP client, the
+			// same page would most likely fail again.
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, repo := range repos {
-			r = append(r, Repository{
-				Provider:      p,
-				Owner:         org,
-				Path:          repo.PathWithNamespace,
-				Name:          repo.Name,
-				CloneURL:      repo.HTTPURLToRepo,
-				SSHUrl:        repo.SSHURLToRepo,
-				DefaultBranch: repo.DefaultBranch,
-			})
+			r = append(r, p.newRepository(org, repo))
 		}
 
 		if resp.NextPage == 0 {
@@ -114,7 +118,7 @@ func (p gitlabProvider) GetUserRepositories(username string) ([]Repository, erro
 
 	users, resp, err := p.client.Users.ListUsers(&gitlab.ListUsersOptions{
 		Username: &username,
-	})
+	}, gitlab.WithContext(p.ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -129,32 +133,21 @@ func (p gitlabProvider) GetUserRepositories(username string) ([]Repository, erro
 			Page:    1,
 			PerPage: 100,
 		},
-		Owned: gitlab.Bool(true),
+		Owned:      gitlab.Bool(true),
+		Statistics: gitlab.Bool(true),
 	}
 
 	for {
 		log.Debugf("Processing page %d for user %s", opt.Page, username)
 
-		repos, resp, err := p.client.Projects.ListUserProjects(user.ID, opt)
+		repos, resp, err := p.client.Projects.ListUserProjects(user.ID, opt, gitlab.WithContext(p.ctx))
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, repo := range repos {
-			r = append(r, Repository{
-				Provider:      p,
-				Owner:         username,
-				Path:          repo.PathWithNamespace,
-				Name:          repo.Name,
-				CloneURL:      repo.HTTPURLToRepo,
-				SSHUrl:        repo.SSHURLToRepo,
-				DefaultBranch: repo.DefaultBranch,
-			})
+			r = append(r, p.newRepository(username, repo))
 		}
 
 		if resp.NextPage == 0 {
@@ -167,6 +160,88 @@ func (p gitlabProvider) GetUserRepositories(username string) ([]Repository, erro
 	return r, errorList
 }
 
+// withStatistics requests the statistics of the projects of a group, which
+// ListGroupProjectsOptions lacks although the API accepts them.
+func withStatistics() gitlab.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		q := req.URL.Query()
+		q.Set("statistics", "true")
+		req.URL.RawQuery = q.Encode()
+
+		return nil
+	}
+}
+
+func (p gitlabProvider) newRepository(owner string, repo *gitlab.Project) Repository {
+	topics := repo.Topics
+	if len(topics) == 0 {
+		// Instances older than GitLab 14.0 only know about tags.
+		topics = repo.TagList
+	}
+
+	// Statistics are only returned to members with at least the Reporter
+	// role.
+	var size int64
+	if repo.Statistics != nil {
+		size = repo.Statistics.RepositorySize
+	}
+
+	// The last activity also covers merge requests and issues, it is the
+	// closest to a push time GitLab provides.
+	var createdAt, lastActivityAt time.Time
+	if repo.CreatedAt != nil {
+		createdAt = *repo.CreatedAt
+	}
+	if repo.LastActivityAt != nil {
+		lastActivityAt = *repo.LastActivityAt
+	}
+
+	return Repository{
+		Provider:      p,
+		Owner:         owner,
+		Path:          repo.PathWithNamespace,
+		Name:          repo.Name,
+		CloneURL:      repo.HTTPURLToRepo,
+		SSHUrl:        repo.SSHURLToRepo,
+		DefaultBranch: repo.DefaultBranch,
+		Description:   repo.Description,
+		Topics:        topics,
+		Language:      p.primaryLanguage(repo),
+		Size:          size,
+		Stars:         repo.StarCount,
+		Fork:          repo.ForkedFromProject != nil,
+		Archived:      repo.Archived,
+		Visibility:    string(repo.Visibility),
+		CreatedAt:     createdAt,
+		UpdatedAt:     lastActivityAt,
+		PushedAt:      lastActivityAt,
+	}
+}
+
+// primaryLanguage returns the language making up the largest share of the
+// project, which costs a request per project and is thus only done on demand.
+func (p gitlabProvider) primaryLanguage(repo *gitlab.Project) string {
+	if !p.languages {
+		return ""
+	}
+
+	languages, _, err := p.client.Projects.GetProjectLanguages(repo.ID, gitlab.WithContext(p.ctx))
+	if err != nil {
+		log.Warnf("Failed to fetch languages of %s: %v", repo.PathWithNamespace, err)
+		return ""
+	}
+
+	var language string
+	var share float32
+	for name, s := range *languages {
+		if s > share || (s == share && name < language) {
+			language, share = name, s
+		}
+	}
+
+	return language
+}
+
 func (p gitlabProvider) getAllOrganizations() ([]string, error) {
 	var errorList error
 	r := []string{}
@@ -179,14 +254,10 @@ func (p gitlabProvider) getAllOrganizations() ([]string, error) {
 	}
 
 	for {
-		groups, resp, err := p.client.Groups.ListGroups(opt)
+		groups, resp, err := p.client.Groups.ListGroups(opt, gitlab.WithContext(p.ctx))
 		if err != nil {
 			errorList = appendError(errorList, err)
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				break
-			}
-
-			continue
+			break
 		}
 
 		for _, g := range groups {
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	log "github.com/sirupsen/logrus"
+)
+
+// localProvider discovers repositories already on disk, without calling any
+// API. The first path segment below the root folder is the owner.
+type localProvider struct {
+	repositories inventory
+}
+
+func newLocalProvider(options ProviderOptions) (*localProvider, error) {
+	root := strings.TrimSpace(options.EndpointUrl)
+	if root == "" {
+		return nil, errors.New("Local provider requires the root folder as endpoint.")
+	}
+
+	entries, err := scanRepositories(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localProvider{entries}, nil
+}
+
+func scanRepositories(root string) (inventory, error) {
+	var entries inventory
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() || !IsRepository(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		rel = strings.TrimSuffix(filepath.ToSlash(rel), ".git")
+		if !strings.Contains(rel, "/") {
+			log.Warnf("Ignoring repository %s: it should be stored below an owner folder", path)
+			return filepath.SkipDir
+		}
+
+		entry, err := readRepository(path)
+		if err != nil {
+			log.Warnf("Ignoring repository %s: %v", path, err)
+			return filepath.SkipDir
+		}
+
+		entry.Owner = rel[0:strings.Index(rel, "/")]
+		entry.Path = rel
+		entry.Name = rel[strings.LastIndex(rel, "/")+1:]
+		entries = append(entries, entry)
+
+		log.Debugf("Found repository %s in %s", rel, path)
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	return entries, nil
+}
+
+// IsRepository reports whether path is a working tree or a bare repository.
+func IsRepository(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return true
+	}
+
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readRepository(path string) (inventoryEntry, error) {
+	var entry inventoryEntry
+
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return entry, err
+	}
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return entry, err
+	}
+	entry.CloneURL, entry.SSHUrl = splitRemoteURL(remote.Config().URLs[0])
+
+	// HEAD is read without being resolved, so that the branch of an empty
+	// repository is still known.
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return entry, err
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		entry.DefaultBranch = head.Target().Short()
+	}
+
+	return entry, nil
+}
+
+// splitRemoteURL returns the HTTPS and SSH clone URLs of a remote, guessing
+// the one that is not configured from the usual hosting conventions.
+func splitRemoteURL(remoteURL string) (cloneURL string, sshURL string) {
+	if u, err := url.Parse(remoteURL); err == nil && u.Host != "" {
+		path := strings.TrimPrefix(u.Path, "/")
+
+		switch u.Scheme {
+		case "http", "https":
+			return remoteURL, "git@" + u.Hostname() + ":" + path
+		case "ssh":
+			return "https://" + u.Hostname() + "/" + path, remoteURL
+		}
+
+		return remoteURL, ""
+	}
+
+	// URLs with a scheme but no host, e.g. file:///srv/repo.git, point to a
+	// local repository. Anything else parsed as a scheme is the host of the
+	// scp-like syntax.
+	if u, err := url.Parse(remoteURL); err == nil && (u.Scheme == "file" || strings.Contains(remoteURL, "://")) {
+		return remoteURL, ""
+	}
+
+	// scp-like syntax: [user@]host:path, the path being free to contain "@"
+	if i := strings.Index(remoteURL, ":"); i > 0 && !strings.Contains(remoteURL[:i], "/") {
+		host := remoteURL[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+
+		return "https://" + host + "/" + remoteURL[i+1:], remoteURL
+	}
+
+	return remoteURL, ""
+}
+
+func (p localProvider) GetName() string {
+	return PROVIDER_LOCAL
+}
+
+func (p localProvider) GetOrganizations(filter []string) ([]string, error) {
+	return p.repositories.owners(filter)
+}
+
+func (p localProvider) GetOrganizationRepositories(org string) ([]Repository, error) {
+	return p.repositories.ownerRepositories(p, org), nil
+}
+
+func (p localProvider) GetUserRepositories(username string) ([]Repository, error) {
+	r := p.repositories.ownerRepositories(p, username)
+	if len(r) == 0 {
+		return nil, fmt.Errorf("user %s not found in local folder", username)
+	}
+
+	return r, nil
+}
@@ -4,11 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 const (
 	PROVIDER_GITHUB = "github"
 	PROVIDER_GITLAB = "gitlab"
+	PROVIDER_GITEA  = "gitea"
+
+	PROVIDER_BITBUCKET        = "bitbucket"
+	PROVIDER_BITBUCKET_SERVER = "bitbucket-server"
+	PROVIDER_AZURE            = "azure"
+	PROVIDER_FILE             = "file"
+	PROVIDER_LOCAL            = "local"
+)
+
+const (
+	VISIBILITY_PUBLIC   = "public"
+	VISIBILITY_PRIVATE  = "private"
+	VISIBILITY_INTERNAL = "internal"
 )
 
 type Provider interface {
@@ -21,7 +36,12 @@ type Provider interface {
 type ProviderOptions struct {
 	Context     context.Context
 	EndpointUrl string
+	Username    string
 	AccessToken string
+	// Languages fetches the primary language of every repository from the
+	// providers which only return it through an extra request per repository
+	// (GitLab).
+	Languages bool
 }
 
 type Repository struct {
@@ -32,6 +52,26 @@ type Repository struct {
 	CloneURL      string
 	SSHUrl        string
 	DefaultBranch string
+
+	Description string
+	Topics      []string
+	// Language is the primary programming language.
+	Language string
+	// Size is the approximate size of the repository in bytes, zero when the
+	// provider does not report it.
+	Size     int64
+	Stars    int
+	Fork     bool
+	Archived bool
+	Template bool
+	// Visibility is one of the VISIBILITY_* constants, or empty when the
+	// provider does not know it.
+	Visibility string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// PushedAt is the time of the last push, as far as the provider tracks
+	// it.
+	PushedAt time.Time
 }
 
 func NewProvider(pType string, options ProviderOptions) (Provider, error) {
@@ -44,6 +84,18 @@ func NewProvider(pType string, options ProviderOptions) (Provider, error) {
 		return newGithubProviderClient(options)
 	case PROVIDER_GITLAB:
 		return newGitlabProviderClient(options)
+	case PROVIDER_GITEA:
+		return newGiteaProviderClient(options)
+	case PROVIDER_BITBUCKET:
+		return newBitbucketProviderClient(options)
+	case PROVIDER_BITBUCKET_SERVER:
+		return newBitbucketServerProviderClient(options)
+	case PROVIDER_AZURE:
+		return newAzureProviderClient(options)
+	case PROVIDER_FILE:
+		return newFileProvider(options)
+	case PROVIDER_LOCAL:
+		return newLocalProvider(options)
 	case "":
 		return nil, errors.New("Provider should be specify.")
 	default:
@@ -51,10 +103,35 @@ func NewProvider(pType string, options ProviderOptions) (Provider, error) {
 	}
 }
 
+// HTTPSUsername returns the username sent along the access token when
+// cloning over HTTPS, as expected by each hosting platform.
+func HTTPSUsername(pType string) string {
+	switch pType {
+	case PROVIDER_GITHUB:
+		return "x-access-token"
+	case PROVIDER_BITBUCKET, PROVIDER_BITBUCKET_SERVER:
+		return "x-token-auth"
+	default:
+		return "oauth2"
+	}
+}
+
 func (r Repository) Fullname() string {
 	return r.Owner + "/" + r.Name
 }
 
+// RelativePath returns where the repository is stored below an output folder.
+// It is the owner followed by the name, unless the path is not rooted at the
+// owner (e.g. Azure DevOps "project/repo"), in which case the whole path is
+// kept to avoid collisions.
+func (r Repository) RelativePath() string {
+	if r.Path == "" || strings.HasPrefix(r.Path, r.Owner+"/") {
+		return r.Owner + "/" + r.Name
+	}
+
+	return r.Owner + "/" + r.Path
+}
+
 func appendError(errorList error, err error) error {
 	if errorList == nil {
 		return errors.New(err.Error())
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type restClient struct {
+	ctx        context.Context
+	baseURL    string
+	httpClient *http.Client
+	authorize  func(req *http.Request)
+}
+
+func newRestClient(ctx context.Context, baseURL string, authorize func(req *http.Request)) restClient {
+	return restClient{
+		ctx:        ctx,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: newRetryClient(),
+		authorize:  authorize,
+	}
+}
+
+// get requests path (relative to the base URL, or absolute as found in
+// pagination links) and decodes the JSON body into v.
+func (c restClient) get(path string, query url.Values, v interface{}) (*http.Response, error) {
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = c.baseURL + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	if v == nil {
+		return resp, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp, fmt.Errorf("GET %s: %w", u, err)
+	}
+
+	return resp, nil
+}
+
+// nextLink returns the URL of the rel="next" entry of the Link header, or an
+// empty string on the last page.
+func nextLink(resp *http.Response) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+
+	return ""
+}
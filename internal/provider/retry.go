@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	retryMaxAttempts = 5
+	retryMinBackoff  = time.Second
+	retryMaxBackoff  = time.Minute
+)
+
+// retryTransport retries the requests failing with a network error, a server
+// error or a rate limit. Rate limited requests are retried once the limit
+// resets, as told by the Retry-After or X-RateLimit-Reset headers, other ones
+// after an exponential backoff.
+type retryTransport struct {
+	base        http.RoundTripper
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// newRetryTransport wraps base, http.DefaultTransport when nil. The backoff
+// grows from minBackoff up to maxBackoff, which default to retryMinBackoff and
+// retryMaxBackoff when zero.
+func newRetryTransport(base http.RoundTripper, minBackoff, maxBackoff time.Duration) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if minBackoff <= 0 {
+		minBackoff = retryMinBackoff
+	}
+
+	if maxBackoff <= 0 {
+		maxBackoff = retryMaxBackoff
+	}
+
+	return &retryTransport{
+		base:        base,
+		maxAttempts: retryMaxAttempts,
+		minBackoff:  minBackoff,
+		maxBackoff:  max(maxBackoff, minBackoff),
+	}
+}
+
+// newRetryClient returns an HTTP client shared by the API clients of the
+// providers.
+func newRetryClient() *http.Client {
+	return &http.Client{Transport: newRetryTransport(nil, 0, 0)}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+
+		delay, retry := t.retryDelay(resp, err, attempt)
+		if !retry || attempt >= t.maxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		// Requests with a body can only be sent again when it can be read
+		// anew.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		if err != nil {
+			log.Warnf("Request to %s failed (%v), retrying in %v (attempt %d/%d)", req.URL.Host, err, delay, attempt+1, t.maxAttempts)
+		} else {
+			log.Warnf("Request to %s failed with status %d, retrying in %v (attempt %d/%d)", req.URL.Host, resp.StatusCode, delay, attempt+1, t.maxAttempts)
+
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay tells whether the request should be retried, and when.
+func (t *retryTransport) retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	backoff := t.minBackoff << (attempt - 1)
+	if backoff > t.maxBackoff || backoff <= 0 {
+		backoff = t.maxBackoff
+	}
+
+	if err != nil {
+		return backoff, true
+	}
+
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (rateLimitHeader(resp, "Remaining") == "0" || resp.Header.Get("Retry-After") != ""))
+
+	if !rateLimited && (resp.StatusCode < 500 || resp.StatusCode == http.StatusNotImplemented) {
+		return 0, false
+	}
+
+	if d, ok := retryAfter(resp); ok {
+		return d, true
+	}
+
+	if rateLimited {
+		if reset, err := strconv.ParseInt(rateLimitHeader(resp, "Reset"), 10, 64); err == nil {
+			// A second is added to absorb the clock skew with the server.
+			if d := time.Until(time.Unix(reset, 0)) + time.Second; d > 0 {
+				return d, true
+			}
+		}
+	}
+
+	return backoff, true
+}
+
+// rateLimitHeader reads a rate limit header, which is prefixed by "X-" on
+// GitHub and Gitea but not on GitLab.
+func rateLimitHeader(resp *http.Response, name string) string {
+	if v := resp.Header.Get("X-RateLimit-" + name); v != "" {
+		return v
+	}
+
+	return resp.Header.Get("RateLimit-" + name)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(v); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newThrottlingServer answers the first requests with the given handler, and
+// the following ones with 200 OK. It returns the number of requests served.
+func newThrottlingServer(t *testing.T, failures int32, fail http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			fail(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func newTestRetryClient() *http.Client {
+	return &http.Client{Transport: newRetryTransport(nil, time.Millisecond, 4*time.Millisecond)}
+}
+
+func get(t *testing.T, client *http.Client, url string) int {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestRetryTooManyRequestsWithRetryAfter(t *testing.T) {
+	server, requests := newThrottlingServer(t, 1, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	if status := get(t, newTestRetryClient(), server.URL); status != http.StatusOK {
+		t.Errorf("status should be 200, got %d", status)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("request should be sent twice, got %d", n)
+	}
+}
+
+func TestRetryForbiddenWithRateLimitExhausted(t *testing.T) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		server, requests := newThrottlingServer(t, 2, func(w http.ResponseWriter, _ *http.Request) {
+			// A reset in the past falls back to the backoff.
+			w.Header().Set(prefix+"Remaining", "0")
+			w.Header().Set(prefix+"Reset", strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		if status := get(t, newTestRetryClient(), server.URL); status != http.StatusOK {
+			t.Errorf("%s: status should be 200, got %d", prefix, status)
+		}
+		if n := requests.Load(); n != 3 {
+			t.Errorf("%s: request should be sent 3 times, got %d", prefix, n)
+		}
+	}
+}
+
+func TestRetryDelayWaitsForRateLimitReset(t *testing.T) {
+	transport := newRetryTransport(nil, time.Millisecond, 4*time.Millisecond)
+	reset := strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10)
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+		resp.Header.Set(prefix+"Remaining", "0")
+		resp.Header.Set(prefix+"Reset", reset)
+
+		delay, retry := transport.retryDelay(resp, nil, 1)
+		if !retry {
+			t.Fatalf("%s: request should be retried", prefix)
+		}
+		if delay < 29*time.Second || delay > 32*time.Second {
+			t.Errorf("%s: delay should last until the reset, got %v", prefix, delay)
+		}
+	}
+}
+
+func TestRetryForbiddenWithoutRateLimit(t *testing.T) {
+	server, requests := newThrottlingServer(t, 1, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	if status := get(t, newTestRetryClient(), server.URL); status != http.StatusForbidden {
+		t.Errorf("status should be 403, got %d", status)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("request should be sent once, got %d", n)
+	}
+}
+
+func TestRetryServerErrorGivesUpAfterMaxAttempts(t *testing.T) {
+	server, requests := newThrottlingServer(t, retryMaxAttempts, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if status := get(t, newTestRetryClient(), server.URL); status != http.StatusBadGateway {
+		t.Errorf("status should be 502, got %d", status)
+	}
+	if n := requests.Load(); n != retryMaxAttempts {
+		t.Errorf("request should be sent %d times, got %d", retryMaxAttempts, n)
+	}
+}
+
+func TestRetryServerErrorRecovers(t *testing.T) {
+	server, requests := newThrottlingServer(t, retryMaxAttempts-1, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	if status := get(t, newTestRetryClient(), server.URL); status != http.StatusOK {
+		t.Errorf("status should be 200, got %d", status)
+	}
+	if n := requests.Load(); n != retryMaxAttempts {
+		t.Errorf("request should be sent %d times, got %d", retryMaxAttempts, n)
+	}
+}
+
+func TestRetryDelayBackoffIsCapped(t *testing.T) {
+	transport := newRetryTransport(nil, time.Millisecond, 4*time.Millisecond)
+	resp := &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{}}
+
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i, want := range expected {
+		if delay, _ := transport.retryDelay(resp, nil, i+1); delay != want {
+			t.Errorf("attempt %d: delay should be %v, got %v", i+1, want, delay)
+		}
+	}
+
+	// The shift overflowing must not lead to a negative or zero delay.
+	if delay, _ := transport.retryDelay(resp, nil, 100); delay != 4*time.Millisecond {
+		t.Errorf("delay should be capped, got %v", delay)
+	}
+}
+
+func TestRetryIgnoresClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusNotImplemented, http.StatusNotFound, http.StatusUnauthorized} {
+		server, requests := newThrottlingServer(t, 1, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(status)
+		})
+
+		if got := get(t, newTestRetryClient(), server.URL); got != status {
+			t.Errorf("status should be %d, got %d", status, got)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("%d: request should be sent once, got %d", status, n)
+		}
+	}
+}
+
+func TestRetryStopsWhenContextIsCanceled(t *testing.T) {
+	server, requests := newThrottlingServer(t, retryMaxAttempts, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = newTestRetryClient().Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error should be the context's one, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("wait should stop with the context, took %v", elapsed)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("request should be sent once, got %d", n)
+	}
+}
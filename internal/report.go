@@ -0,0 +1,208 @@
+package vacuum
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	REPORT_JSON  = "json"
+	REPORT_JUNIT = "junit"
+)
+
+type report struct {
+	StartedAt    time.Time          `json:"started_at"`
+	Duration     float64            `json:"duration"`
+	Totals       reportTotals       `json:"totals"`
+	Owners       []reportOwner      `json:"owners"`
+	Repositories []reportRepository `json:"repositories"`
+}
+
+type reportTotals struct {
+	Repositories int   `json:"repositories"`
+	Succeeded    int   `json:"succeeded"`
+	Skipped      int   `json:"skipped"`
+	Failed       int   `json:"failed"`
+	Bytes        int64 `json:"bytes"`
+}
+
+// reportOwner holds the totals of the repositories of an organization or a
+// user.
+type reportOwner struct {
+	Target string `json:"target"`
+	Owner  string `json:"owner"`
+	reportTotals
+}
+
+type reportRepository struct {
+	Target    string  `json:"target"`
+	Owner     string  `json:"owner"`
+	Path      string  `json:"path"`
+	Status    string  `json:"status"`
+	Reason    string  `json:"reason,omitempty"`
+	Duration  float64 `json:"duration"`
+	Transport string  `json:"transport,omitempty"`
+	Bytes     int64   `json:"bytes"`
+	Commit    string  `json:"commit,omitempty"`
+}
+
+func newReport(result Result, startTime time.Time) report {
+	r := report{
+		StartedAt:    startTime,
+		Duration:     time.Since(startTime).Seconds(),
+		Owners:       []reportOwner{},
+		Repositories: []reportRepository{},
+	}
+
+	owners := map[[2]string]int{}
+	for _, repo := range result.Repositories {
+		r.Repositories = append(r.Repositories, reportRepository{
+			Target:    repo.Target,
+			Owner:     repo.Repository.Owner,
+			Path:      repo.Repository.Path,
+			Status:    repo.Status,
+			Reason:    repo.Reason,
+			Duration:  repo.Duration.Seconds(),
+			Transport: repo.Transport,
+			Bytes:     repo.Bytes,
+			Commit:    repo.Commit,
+		})
+
+		key := [2]string{repo.Target, repo.Repository.Owner}
+		i, exists := owners[key]
+		if !exists {
+			i = len(r.Owners)
+			owners[key] = i
+			r.Owners = append(r.Owners, reportOwner{Target: repo.Target, Owner: repo.Repository.Owner})
+		}
+
+		r.Totals.add(repo)
+		r.Owners[i].add(repo)
+	}
+
+	return r
+}
+
+func (t *reportTotals) add(repo RepositoryResult) {
+	t.Repositories++
+	t.Bytes += repo.Bytes
+
+	switch repo.Status {
+	case STATUS_SUCCEEDED:
+		t.Succeeded++
+	case STATUS_SKIPPED:
+		t.Skipped++
+	case STATUS_FAILED:
+		t.Failed++
+	}
+}
+
+// writeReport writes the outcome of every repository to path, as JSON or as
+// a JUnit XML file in which each organization or user is a test suite.
+func writeReport(path string, format string, result Result, startTime time.Time) error {
+	r := newReport(result, startTime)
+
+	var data []byte
+	var err error
+
+	switch format {
+	case "", REPORT_JSON:
+		data, err = json.MarshalIndent(r, "", "  ")
+	case REPORT_JUNIT:
+		data, err = xml.MarshalIndent(newJunitReport(r), "", "  ")
+		data = append([]byte(xml.Header), data...)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func newJunitReport(r report) junitTestSuites {
+	suites := junitTestSuites{
+		Name:     "github-vacuum",
+		Tests:    r.Totals.Repositories,
+		Failures: r.Totals.Failed,
+		Skipped:  r.Totals.Skipped,
+		Time:     r.Duration,
+	}
+
+	for _, owner := range r.Owners {
+		suite := junitTestSuite{
+			Name:     owner.Target + "/" + owner.Owner,
+			Tests:    owner.Repositories,
+			Failures: owner.Failed,
+			Skipped:  owner.Skipped,
+		}
+
+		for _, repo := range r.Repositories {
+			if repo.Target != owner.Target || repo.Owner != owner.Owner {
+				continue
+			}
+
+			c := junitTestCase{
+				Name:      repo.Path,
+				ClassName: owner.Target + "." + owner.Owner,
+				Time:      repo.Duration,
+			}
+
+			switch repo.Status {
+			case STATUS_FAILED:
+				c.Failure = &junitMessage{repo.Reason}
+			case STATUS_SKIPPED:
+				c.Skipped = &junitMessage{repo.Reason}
+			}
+
+			if repo.Transport != "" {
+				c.SystemOut = fmt.Sprintf("transport: %s, bytes: %d", repo.Transport, repo.Bytes)
+			}
+
+			suite.Time += repo.Duration
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return suites
+}
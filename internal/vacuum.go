@@ -1,6 +1,7 @@
 package vacuum
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -10,11 +11,239 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func Handle(p provider.Provider, o output.Output, orgsFilter []string, usernamesFilter []string) error {
+// Target is a provider to vacuum, along with the output its repositories
+// are handed to.
+type Target struct {
+	Name      string
+	Provider  provider.Provider
+	Output    output.Output
+	Orgs      []string
+	Usernames []string
+	// Filter drops repositories before they reach the output, every
+	// repository is kept when it is nil.
+	Filter *Filter
+}
+
+type Options struct {
+	// Context interrupts the run once it is done: no more repositories are
+	// listed nor handed over to the outputs, which are still flushed.
+	Context     context.Context
+	Concurrency int
+	// ReportFile receives the outcome of every repository at the end of the
+	// run, formatted as ReportFormat (REPORT_JSON by default).
+	ReportFile   string
+	ReportFormat string
+}
+
+const (
+	STATUS_SUCCEEDED = "succeeded"
+	STATUS_SKIPPED   = "skipped"
+	STATUS_FAILED    = "failed"
+)
+
+// RepositoryResult is the outcome of a repository handed over to the output
+// of a target.
+type RepositoryResult struct {
+	Target     string
+	Repository provider.Repository
+	// Status is one of the STATUS_* constants.
+	Status string
+	// Reason tells why the repository was skipped, or the error it failed
+	// with.
+	Reason    string
+	Duration  time.Duration
+	Transport string
+	Bytes     int64
+	// Commit is the SHA reached by the repository, when the output knows it.
+	Commit string
+}
+
+// Result gathers the outcome of every repository of a run, in the order they
+// were processed.
+type Result struct {
+	Repositories []RepositoryResult
+	// Interrupted runs did not process every repository.
+	Interrupted bool
+}
+
+// Count returns the number of repositories with the given status.
+func (r Result) Count(status string) int {
+	count := 0
+	for _, repo := range r.Repositories {
+		if repo.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Failed tells whether any repository failed.
+func (r Result) Failed() bool {
+	return r.Count(STATUS_FAILED) > 0
+}
+
+type targetSummary struct {
+	repos     int
+	filtered  int
+	errors    int
+	succeeded int
+	skipped   int
+	failed    int
+	// listings are the owners whose repositories were listed without error,
+	// the only ones which can be pruned.
+	listings []ownerListing
+}
+
+type ownerListing struct {
+	owner string
+	repos []provider.Repository
+}
+
+// Handle vacuums every target. Per-repository outcomes are returned in the
+// result, while the error gathers the failures to list repositories or to
+// flush outputs.
+func Handle(targets []Target, opts Options) (Result, error) {
+	switch opts.ReportFormat {
+	case "", REPORT_JSON, REPORT_JUNIT:
+	default:
+		return Result{}, errors.New("Unknown report format.")
+	}
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	var errorList error
 	startTime := time.Now()
-	totalRepos := 0
-	processedRepos := 0
+	summaries := make([]targetSummary, len(targets))
+
+	workers := newWorkerPool(ctx, opts.Concurrency)
+
+	for i, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if len(targets) > 1 {
+			log.Infof("[%d/%d] Processing target: %s", i+1, len(targets), t.Name)
+		}
+
+		summaries[i] = handleTarget(ctx, workers, t, &errorList)
+	}
+
+	result := Result{Repositories: workers.wait()}
+
+	listedRepos := 0
+	for _, s := range summaries {
+		listedRepos += s.repos
+	}
+
+	if ctx.Err() != nil {
+		result.Interrupted = true
+		log.Warnf("Operation interrupted, %d listed repository(ies) were not processed", listedRepos-len(result.Repositories))
+	}
+
+	for _, repo := range result.Repositories {
+		for i, t := range targets {
+			if t.Name != repo.Target {
+				continue
+			}
+
+			switch repo.Status {
+			case STATUS_SUCCEEDED:
+				summaries[i].succeeded++
+			case STATUS_SKIPPED:
+				summaries[i].skipped++
+			case STATUS_FAILED:
+				summaries[i].failed++
+			}
+			break
+		}
+	}
+
+	// An interrupted run did not list every owner, nor handled every
+	// repository, so nothing is pruned.
+	if !result.Interrupted {
+		for i, t := range targets {
+			pruner, ok := t.Output.(output.Pruner)
+			if !ok {
+				continue
+			}
+
+			for _, l := range summaries[i].listings {
+				if err := pruner.Prune(l.owner, l.repos); err != nil {
+					log.Error("Error pruning output: ", err.Error())
+					errorList = appendError(errorList, err)
+					summaries[i].errors++
+				}
+			}
+		}
+	}
+
+	for i, t := range targets {
+		log.Infof("Flushing output of %s...", t.Name)
+		if err := t.Output.Flush(); err != nil {
+			log.Error("Error flushing output: ", err.Error())
+			errorList = appendError(errorList, err)
+			summaries[i].errors++
+		}
+	}
+
+	duration := time.Since(startTime)
+	log.Infof("Vacuum operation completed in %v", duration)
+	if len(targets) > 1 {
+		for i, t := range targets {
+			s := summaries[i]
+			log.Infof("%s: %d repository(ies), %d filtered out, %d succeeded, %d skipped, %d failed, %d error(s)", t.Name, s.repos, s.filtered, s.succeeded, s.skipped, s.failed, s.errors)
+		}
+	}
+	log.Infof("Processed %d repositories total: %d succeeded, %d skipped, %d failed", len(result.Repositories), result.Count(STATUS_SUCCEEDED), result.Count(STATUS_SKIPPED), result.Count(STATUS_FAILED))
+
+	if opts.ReportFile != "" {
+		if err := writeReport(opts.ReportFile, opts.ReportFormat, result, startTime); err != nil {
+			log.Error("Error writing report: ", err.Error())
+			errorList = appendError(errorList, err)
+		} else {
+			log.Infof("Report written to %s", opts.ReportFile)
+		}
+	}
+
+	if errorList != nil || result.Failed() {
+		log.Warn("Operation completed with errors")
+	} else {
+		log.Info("Operation completed successfully")
+	}
+
+	return result, errorList
+}
+
+func handleTarget(ctx context.Context, workers *workerPool, t Target, errorList *error) targetSummary {
+	var summary targetSummary
+	p := t.Provider
+	orgsFilter := t.Orgs
+	usernamesFilter := t.Usernames
+
+	fail := func(err error) {
+		*errorList = appendError(*errorList, err)
+		summary.errors++
+	}
+
+	submit := func(owner string, repos []provider.Repository, err error) {
+		if err == nil {
+			summary.listings = append(summary.listings, ownerListing{owner, repos})
+		}
+
+		kept := t.Filter.apply(repos)
+		if len(kept) < len(repos) {
+			log.Infof("Filtered out %d repository(ies)", len(repos)-len(kept))
+		}
+
+		summary.repos += len(kept)
+		summary.filtered += len(repos) - len(kept)
+		workers.submit(t, kept)
+	}
 
 	log.Infof("Starting vacuum operation with provider: %s", p.GetName())
 
@@ -26,67 +255,47 @@ func Handle(p provider.Provider, o output.Output, orgsFilter []string, usernames
 
 		if err != nil {
 			log.Error("Error fetching organizations: ", err.Error())
-			errorList = appendError(errorList, err)
+			fail(err)
 		}
 
 		for orgIdx, org := range orgs {
+			if ctx.Err() != nil {
+				break
+			}
+
 			log.Infof("[%d/%d] Processing organization: %s", orgIdx+1, len(orgs), org)
 
 			repos, err := p.GetOrganizationRepositories(org)
-			repoCount := len(repos)
-			totalRepos += repoCount
-			log.Infof("Found %d repository(ies) in organization %s", repoCount, org)
+			log.Infof("Found %d repository(ies) in organization %s", len(repos), org)
 
 			if err != nil {
 				log.Error("Error fetching repositories for org ", org, ": ", err.Error())
-				errorList = appendError(errorList, err)
+				fail(err)
 			}
 
-			for repoIdx, repo := range repos {
-				processedRepos++
-				log.Infof("[%d/%d] Processing repository: %s (%d/%d total)", repoIdx+1, repoCount, repo.Fullname(), processedRepos, totalRepos)
-				o.Handle(repo)
-			}
+			submit(org, repos, err)
 		}
 	}
 
 	for userIdx, username := range usernamesFilter {
+		if ctx.Err() != nil {
+			break
+		}
+
 		log.Infof("[%d/%d] Processing user: %s", userIdx+1, len(usernamesFilter), username)
 
 		repos, err := p.GetUserRepositories(username)
-		repoCount := len(repos)
-		totalRepos += repoCount
-		log.Infof("Found %d repository(ies) for user %s", repoCount, username)
+		log.Infof("Found %d repository(ies) for user %s", len(repos), username)
 
 		if err != nil {
 			log.Error("Error fetching repositories for user ", username, ": ", err.Error())
-			errorList = appendError(errorList, err)
-		}
-
-		for repoIdx, repo := range repos {
-			processedRepos++
-			log.Infof("[%d/%d] Processing repository: %s (%d/%d total)", repoIdx+1, repoCount, repo.Fullname(), processedRepos, totalRepos)
-			o.Handle(repo)
+			fail(err)
 		}
-	}
-
-	log.Info("Flushing output...")
-	if err := o.Flush(); err != nil {
-		log.Error("Error flushing output: ", err.Error())
-		errorList = appendError(errorList, err)
-	}
 
-	duration := time.Since(startTime)
-	log.Infof("Vacuum operation completed in %v", duration)
-	log.Infof("Processed %d repositories total", processedRepos)
-
-	if errorList != nil {
-		log.Warn("Operation completed with errors")
-	} else {
-		log.Info("Operation completed successfully")
+		submit(username, repos, err)
 	}
 
-	return errorList
+	return summary
 }
 
 func appendError(errorList error, err error) error {
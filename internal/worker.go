@@ -0,0 +1,118 @@
+package vacuum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jdecool/github-vacuum/internal/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+// workerPool hands repositories over to their output from a bounded number
+// of goroutines. Submitting blocks while every worker is busy.
+type workerPool struct {
+	ctx  context.Context
+	jobs chan job
+	wg   sync.WaitGroup
+
+	mu             sync.Mutex
+	totalRepos     int
+	processedRepos int
+	results        []RepositoryResult
+}
+
+type job struct {
+	target Target
+	repo   provider.Repository
+	index  int
+	count  int
+}
+
+func newWorkerPool(ctx context.Context, concurrency int) *workerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	wp := &workerPool{
+		ctx:  ctx,
+		jobs: make(chan job),
+	}
+
+	wp.wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go wp.work()
+	}
+
+	return wp
+}
+
+func (wp *workerPool) work() {
+	defer wp.wg.Done()
+
+	for j := range wp.jobs {
+		wp.mu.Lock()
+		wp.processedRepos++
+		log.Infof("[%d/%d] Processing repository: %s (%d/%d total)", j.index+1, j.count, j.repo.Fullname(), wp.processedRepos, wp.totalRepos)
+		wp.mu.Unlock()
+
+		result := handle(j.target, j.repo)
+
+		wp.mu.Lock()
+		wp.results = append(wp.results, result)
+		wp.mu.Unlock()
+	}
+}
+
+func handle(t Target, r provider.Repository) RepositoryResult {
+	startTime := time.Now()
+	res, err := t.Output.Handle(r)
+
+	result := RepositoryResult{
+		Target:     t.Name,
+		Repository: r,
+		Status:     STATUS_SUCCEEDED,
+		Duration:   time.Since(startTime),
+		Transport:  res.Transport,
+		Bytes:      res.Bytes,
+		Commit:     res.Commit,
+	}
+
+	switch {
+	case err != nil:
+		log.Errorf("Failed to process repository %s: %v", r.Fullname(), err)
+		result.Status = STATUS_FAILED
+		result.Reason = err.Error()
+	case res.Skipped:
+		log.Warnf("Skipped repository %s: %s", r.Fullname(), res.Reason)
+		result.Status = STATUS_SKIPPED
+		result.Reason = res.Reason
+	}
+
+	return result
+}
+
+// submit queues the repositories listed for an organization or a user. It
+// gives up once the context of the pool is done.
+func (wp *workerPool) submit(t Target, repos []provider.Repository) {
+	wp.mu.Lock()
+	wp.totalRepos += len(repos)
+	wp.mu.Unlock()
+
+	for i, repo := range repos {
+		select {
+		case wp.jobs <- job{t, repo, i, len(repos)}:
+		case <-wp.ctx.Done():
+			return
+		}
+	}
+}
+
+// wait blocks until every submitted repository has been handled, and
+// returns their results.
+func (wp *workerPool) wait() []RepositoryResult {
+	close(wp.jobs)
+	wp.wg.Wait()
+
+	return wp.results
+}
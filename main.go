@@ -3,8 +3,16 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 
 	vacuum "github.com/jdecool/github-vacuum/internal"
+	"github.com/jdecool/github-vacuum/internal/config"
 	"github.com/jdecool/github-vacuum/internal/output"
 	"github.com/jdecool/github-vacuum/internal/provider"
 	log "github.com/sirupsen/logrus"
@@ -12,14 +20,41 @@ import (
 
 func main() {
 	var (
+		configPath          string
 		providerType        string
 		providerEndpoint    string
+		providerUsername    string
 		providerAccessToken string
-		outputFormat        string
+		outputFormats       = []string{}
 		outputFolder        string
-		sshKeyPath          string
+		outputFile          string
+		outputColumns       string
+		sshKeyPaths         = []string{}
+		sshPassphraseFile   string
+		sshPassphraseEnv    string
+		knownHostsPath      string
+		hostKeyPolicy       string
+		sync                bool
+		mirror              bool
+		depth               int
+		singleBranch        bool
+		skipUnchanged       bool
+		prune               bool
+		prunePolicy         string
+		concurrency         int
+		reportFile          string
+		reportFormat        string
+		stateFile           string
 		orgsFilter          = []string{}
 		usernamesFilter     = []string{}
+		includes            = []string{}
+		excludes            = []string{}
+		includeRegexps      = []string{}
+		excludeRegexps      = []string{}
+		skipForks           bool
+		skipArchived        bool
+		visibility          string
+		fetchLanguages      bool
 		debug               bool
 		quiet               bool
 	)
@@ -34,16 +69,55 @@ func main() {
 		return nil
 	}
 
+	appendSSHKey := func(path string) error {
+		sshKeyPaths = append(sshKeyPaths, path)
+		return nil
+	}
+
+	appendTo := func(list *[]string) func(string) error {
+		return func(value string) error {
+			*list = append(*list, value)
+			return nil
+		}
+	}
+
+	flag.StringVar(&configPath, "config", "", "")
 	flag.StringVar(&providerType, "provider", "", "")
 	flag.StringVar(&providerEndpoint, "provider-endpoint", "", "")
+	flag.StringVar(&providerUsername, "provider-username", "", "")
 	flag.StringVar(&providerAccessToken, "provider-access-token", "", "")
-	flag.StringVar(&outputFormat, "output", output.OUTPUT_FILESYSTEM, "")
+	flag.Func("output", "", appendTo(&outputFormats))
 	flag.StringVar(&outputFolder, "output-folder", "", "")
-	flag.StringVar(&sshKeyPath, "ssh-key", "", "")
+	flag.StringVar(&outputFile, "output-file", "", "")
+	flag.StringVar(&outputColumns, "output-columns", "", "")
+	flag.Func("ssh-key", "", appendSSHKey)
+	flag.StringVar(&sshPassphraseFile, "ssh-key-passphrase-file", "", "")
+	flag.StringVar(&sshPassphraseEnv, "ssh-key-passphrase-env", "", "")
+	flag.StringVar(&knownHostsPath, "known-hosts", "", "")
+	flag.StringVar(&hostKeyPolicy, "ssh-host-key-policy", output.HOST_KEY_POLICY_STRICT, "")
+	flag.BoolVar(&sync, "sync", false, "")
+	flag.BoolVar(&mirror, "mirror", false, "")
+	flag.IntVar(&depth, "depth", 0, "")
+	flag.BoolVar(&singleBranch, "single-branch", false, "")
+	flag.BoolVar(&skipUnchanged, "skip-unchanged", false, "")
+	flag.BoolVar(&prune, "prune", false, "")
+	flag.StringVar(&prunePolicy, "prune-policy", output.PRUNE_POLICY_REPORT, "")
+	flag.IntVar(&concurrency, "concurrency", 1, "")
+	flag.StringVar(&reportFile, "report-file", "", "")
+	flag.StringVar(&reportFormat, "report-format", vacuum.REPORT_JSON, "")
+	flag.StringVar(&stateFile, "state-file", "", "")
 	flag.BoolVar(&debug, "debug", false, "")
 	flag.BoolVar(&quiet, "quiet", false, "")
 	flag.Func("org", "", appendOrg)
 	flag.Func("username", "", appendUsername)
+	flag.Func("include", "", appendTo(&includes))
+	flag.Func("exclude", "", appendTo(&excludes))
+	flag.Func("include-regex", "", appendTo(&includeRegexps))
+	flag.Func("exclude-regex", "", appendTo(&excludeRegexps))
+	flag.BoolVar(&skipForks, "skip-forks", false, "")
+	flag.BoolVar(&skipArchived, "skip-archived", false, "")
+	flag.StringVar(&visibility, "visibility", "", "")
+	flag.BoolVar(&fetchLanguages, "fetch-languages", false, "")
 	flag.Parse()
 
 	log.SetFormatter(&log.TextFormatter{
@@ -59,25 +133,296 @@ func main() {
 		log.SetLevel(log.InfoLevel)
 	}
 
-	p, err := provider.NewProvider(providerType, provider.ProviderOptions{
-		Context:     context.Background(),
-		EndpointUrl: providerEndpoint,
-		AccessToken: providerAccessToken,
+	// Each flag overrides the matching setting of every configured provider.
+	overrides := map[string]func(p *config.Provider){
+		"provider":                func(p *config.Provider) { p.Type = providerType },
+		"provider-endpoint":       func(p *config.Provider) { p.Endpoint = providerEndpoint },
+		"provider-username":       func(p *config.Provider) { p.Username = providerUsername },
+		"provider-access-token":   func(p *config.Provider) { p.AccessToken = providerAccessToken },
+		"org":                     func(p *config.Provider) { p.Orgs = orgsFilter },
+		"username":                func(p *config.Provider) { p.Users = usernamesFilter },
+		"fetch-languages":         func(p *config.Provider) { p.FetchLanguages = fetchLanguages },
+		"include":                 func(p *config.Provider) { p.Filters.Include = includes },
+		"exclude":                 func(p *config.Provider) { p.Filters.Exclude = excludes },
+		"include-regex":           func(p *config.Provider) { p.Filters.IncludeRegex = includeRegexps },
+		"exclude-regex":           func(p *config.Provider) { p.Filters.ExcludeRegex = excludeRegexps },
+		"skip-forks":              func(p *config.Provider) { p.Filters.SkipForks = skipForks },
+		"skip-archived":           func(p *config.Provider) { p.Filters.SkipArchived = skipArchived },
+		"visibility":              func(p *config.Provider) { p.Filters.Visibility = visibility },
+		"output":                  func(p *config.Provider) { p.Outputs = withFormats(p.Outputs, outputFormats) },
+		"output-folder":           eachOutput(func(o *config.Output) { o.Folder = outputFolder }),
+		"output-file":             eachOutput(func(o *config.Output) { o.File = outputFile }),
+		"output-columns":          eachOutput(func(o *config.Output) { o.Columns = splitList(outputColumns) }),
+		"ssh-key":                 eachOutput(func(o *config.Output) { o.SSHKeys = sshKeyPaths }),
+		"ssh-key-passphrase-file": eachOutput(func(o *config.Output) { o.SSHKeyPassphraseFile = sshPassphraseFile }),
+		"ssh-key-passphrase-env":  eachOutput(func(o *config.Output) { o.SSHKeyPassphraseEnv = sshPassphraseEnv }),
+		"known-hosts":             eachOutput(func(o *config.Output) { o.KnownHosts = knownHostsPath }),
+		"ssh-host-key-policy":     eachOutput(func(o *config.Output) { o.HostKeyPolicy = hostKeyPolicy }),
+		"sync":                    eachOutput(func(o *config.Output) { o.Sync = sync }),
+		"mirror":                  eachOutput(func(o *config.Output) { o.Mirror = mirror }),
+		"depth":                   eachOutput(func(o *config.Output) { o.Depth = depth }),
+		"single-branch":           eachOutput(func(o *config.Output) { o.SingleBranch = singleBranch }),
+		"skip-unchanged":          eachOutput(func(o *config.Output) { o.SkipUnchanged = skipUnchanged }),
+		"prune":                   eachOutput(func(o *config.Output) { o.Prune = prune }),
+		"prune-policy":            eachOutput(func(o *config.Output) { o.PrunePolicy = prunePolicy }),
+	}
+
+	cfg := &config.Config{
+		Concurrency:  concurrency,
+		ReportFile:   reportFile,
+		ReportFormat: reportFormat,
+		StateFile:    stateFile,
+		Providers:    []config.Provider{{Outputs: []config.Output{{}}}},
+	}
+
+	if configPath == "" {
+		for _, apply := range overrides {
+			apply(&cfg.Providers[0])
+		}
+	} else {
+		var err error
+		cfg, err = config.Load(configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "concurrency":
+				cfg.Concurrency = concurrency
+			case "report-file":
+				cfg.ReportFile = reportFile
+			case "report-format":
+				cfg.ReportFormat = reportFormat
+			case "state-file":
+				cfg.StateFile = stateFile
+			}
+
+			if apply, exists := overrides[f.Name]; exists {
+				for i := range cfg.Providers {
+					apply(&cfg.Providers[i])
+				}
+			}
+		})
+	}
+
+	// A first signal lets the in-flight clones finish or abort before the
+	// outputs are flushed, a second one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Warn("Interrupting, press Ctrl-C again to exit immediately")
+	}()
+
+	if err := checkPrunedFolders(cfg.Providers); err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := output.LoadState(cfg.StateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Outputs writing to the same file, or to the standard output, would
+	// overwrite each other, whichever provider they belong to.
+	files := map[string]bool{}
+
+	targets := []vacuum.Target{}
+	for _, p := range cfg.Providers {
+		t, err := newTarget(ctx, p, state, files)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		targets = append(targets, t)
+	}
+
+	result, err := vacuum.Handle(targets, vacuum.Options{
+		Context:      ctx,
+		Concurrency:  cfg.Concurrency,
+		ReportFile:   cfg.ReportFile,
+		ReportFormat: cfg.ReportFormat,
 	})
 	if err != nil {
-		panic(err)
+		log.Error(err)
 	}
 
-	o, err := output.NewOutput(outputFormat, output.OutputOptions{
-		Folder:     outputFolder,
-		SSHKeyPath: sshKeyPath,
+	if result.Interrupted {
+		os.Exit(130)
+	}
+
+	if err != nil || result.Failed() {
+		os.Exit(1)
+	}
+}
+
+func newTarget(ctx context.Context, c config.Provider, state *output.State, files map[string]bool) (vacuum.Target, error) {
+	p, err := provider.NewProvider(c.Type, provider.ProviderOptions{
+		Context:     ctx,
+		EndpointUrl: c.Endpoint,
+		Username:    c.Username,
+		AccessToken: c.AccessToken,
+		Languages:   c.FetchLanguages,
 	})
 	if err != nil {
-		panic(err)
+		return vacuum.Target{}, err
 	}
 
-	err = vacuum.Handle(p, o, orgsFilter, usernamesFilter)
+	filter, err := vacuum.NewFilter(vacuum.FilterOptions{
+		Include:      c.Filters.Include,
+		Exclude:      c.Filters.Exclude,
+		IncludeRegex: c.Filters.IncludeRegex,
+		ExcludeRegex: c.Filters.ExcludeRegex,
+		SkipForks:    c.Filters.SkipForks,
+		SkipArchived: c.Filters.SkipArchived,
+		Visibility:   c.Filters.Visibility,
+	})
 	if err != nil {
-		panic(err)
+		return vacuum.Target{}, err
 	}
+
+	outputs := []output.Output{}
+	for _, oc := range c.Outputs {
+		format := oc.Format
+		if format == "" {
+			format = output.OUTPUT_FILESYSTEM
+		}
+
+		// The output file is shared by the outputs given on the command line,
+		// while only inventories use it. SQLite databases are the exception,
+		// their rows being keyed by target.
+		usesFile := format != output.OUTPUT_FILESYSTEM && format != output.OUTPUT_NIL && format != output.OUTPUT_SQLITE
+		if usesFile {
+			file := strings.TrimSpace(oc.File)
+			if file == "" || file == "-" {
+				file = "the standard output"
+			} else if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+
+			if files[file] {
+				return vacuum.Target{}, fmt.Errorf("output of %s writes to %s, as another output does", c.Label(), file)
+			}
+			files[file] = true
+		}
+
+		o, err := output.NewOutput(format, output.OutputOptions{
+			Context:              ctx,
+			Target:               c.Label(),
+			File:                 oc.File,
+			Columns:              oc.Columns,
+			Folder:               oc.Folder,
+			SSHKeyPaths:          oc.SSHKeys,
+			SSHKeyPassphraseFile: oc.SSHKeyPassphraseFile,
+			SSHKeyPassphraseEnv:  oc.SSHKeyPassphraseEnv,
+			KnownHostsPath:       oc.KnownHosts,
+			HostKeyPolicy:        oc.HostKeyPolicy,
+			Sync:                 oc.Sync,
+			Mirror:               oc.Mirror,
+			Depth:                oc.Depth,
+			SingleBranch:         oc.SingleBranch,
+			SkipUnchanged:        oc.SkipUnchanged,
+			Prune:                oc.Prune,
+			PrunePolicy:          oc.PrunePolicy,
+			State:                state,
+			Username:             c.Username,
+			AccessToken:          c.AccessToken,
+		})
+		if err != nil {
+			return vacuum.Target{}, err
+		}
+
+		outputs = append(outputs, o)
+	}
+
+	o := outputs[0]
+	if len(outputs) > 1 {
+		o = output.NewMultiOutput(outputs...)
+	}
+
+	return vacuum.Target{
+		Name:      c.Label(),
+		Provider:  p,
+		Output:    o,
+		Orgs:      c.Orgs,
+		Usernames: c.Users,
+		Filter:    filter,
+	}, nil
+}
+
+// checkPrunedFolders rejects the providers pruning a folder which another
+// provider clones to, as each one would take the repositories of the other
+// for orphans when they share an owner.
+func checkPrunedFolders(providers []config.Provider) error {
+	owners := map[string][]string{}
+	pruners := map[string]string{}
+
+	for _, p := range providers {
+		for _, o := range p.Outputs {
+			if o.Format != "" && o.Format != output.OUTPUT_FILESYSTEM {
+				continue
+			}
+
+			folder, err := filepath.Abs(o.Folder)
+			if err != nil {
+				folder = filepath.Clean(o.Folder)
+			}
+
+			if !slices.Contains(owners[folder], p.Label()) {
+				owners[folder] = append(owners[folder], p.Label())
+			}
+			if o.Prune && pruners[folder] == "" {
+				pruners[folder] = p.Label()
+			}
+		}
+	}
+
+	for folder, pruner := range pruners {
+		for _, label := range owners[folder] {
+			if label != pruner {
+				return fmt.Errorf("%s prunes %s, which %s also clones to", pruner, folder, label)
+			}
+		}
+	}
+
+	return nil
+}
+
+// eachOutput applies an override to every output of a provider.
+func eachOutput(apply func(o *config.Output)) func(p *config.Provider) {
+	return func(p *config.Provider) {
+		for i := range p.Outputs {
+			apply(&p.Outputs[i])
+		}
+	}
+}
+
+// withFormats replaces the outputs of a provider by one output per format,
+// each one starting from the settings of the first output.
+func withFormats(outputs []config.Output, formats []string) []config.Output {
+	if len(formats) == 0 {
+		return outputs
+	}
+
+	r := []config.Output{}
+	for _, format := range formats {
+		o := outputs[0]
+		o.Format = format
+		r = append(r, o)
+	}
+
+	return r
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }